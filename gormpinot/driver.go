@@ -72,24 +72,25 @@ func (c *pinotConn) QueryContext(ctx context.Context, query string, args []drive
 }
 
 func (c *pinotConn) query(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	if ctx != nil {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
 	values := namedValuesToInterfaces(args)
 	table := c.defaultTable
 	if table == "" {
 		table = extractTableFromSQL(query)
 	}
-	resp, err := c.conn.ExecuteSQLWithParams(table, query, values)
+	resp, err := c.conn.ExecuteSQLWithParamsContext(ctx, table, query, values)
 	if err != nil {
 		return nil, err
 	}
-	if len(resp.Exceptions) > 0 {
-		return nil, fmt.Errorf("pinot query failed: %s", resp.Exceptions[0].Message)
+	if err := resp.Err(); err != nil {
+		return nil, fmt.Errorf("pinot query failed: %w", err)
 	}
 	if resp.ResultTable != nil {
 		return newResultTableRows(resp.ResultTable), nil
@@ -167,7 +167,7 @@ func TestPinotConnQuerySelectionResults(t *testing.T) {
 func TestPinotConnQueryExceptions(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		_, err := w.Write([]byte(`{"exceptions":[{"message":"bad query"}]}`))
+		_, err := w.Write([]byte(`{"exceptions":[{"errorCode":150,"message":"bad query"}]}`))
 		require.NoError(t, err)
 	}))
 	defer server.Close()
@@ -183,6 +183,9 @@ func TestPinotConnQueryExceptions(t *testing.T) {
 
 	_, err = pConn.QueryContext(context.Background(), "select * from baseballStats", nil)
 	require.Error(t, err)
+	var queryException *pinot.QueryException
+	require.ErrorAs(t, err, &queryException)
+	require.Equal(t, pinot.SQLParsingErrorCode, queryException.ErrorCode)
 }
 
 func TestPinotConnQueryMissingResults(t *testing.T) {
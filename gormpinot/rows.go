@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"strings"
+	"time"
 
 	"github.com/startreedata/pinot-client-go/pinot"
 )
@@ -107,6 +109,11 @@ func convertValue(value interface{}, columnType string) (driver.Value, error) {
 		return int64(v), nil
 	case bool:
 		return v, nil
+	case time.Time:
+		return v, nil
+	case *big.Rat:
+		// Arrow decimals: return the decimal string of the JSON encoding.
+		return new(big.Float).SetRat(v).Text('f', -1), nil
 	case []byte:
 		return v, nil
 	case string:
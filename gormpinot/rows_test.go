@@ -4,7 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"io"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -158,6 +160,15 @@ func TestConvertValueTypes(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, value)
 
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	value, err = convertValue(ts, "TIMESTAMP")
+	require.NoError(t, err)
+	require.Equal(t, ts, value)
+
+	value, err = convertValue(big.NewRat(2501, 20), "BIG_DECIMAL")
+	require.NoError(t, err)
+	require.Equal(t, "125.05", value)
+
 	value, err = convertValue(uint(7), "LONG")
 	require.NoError(t, err)
 	require.Equal(t, int64(7), value)
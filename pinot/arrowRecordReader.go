@@ -0,0 +1,261 @@
+package pinot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+var _ array.RecordReader = (*ArrowRecordReader)(nil)
+
+// arrowTransport is implemented by transports able to return query results as Arrow records.
+type arrowTransport interface {
+	arrowEnabled() bool
+	arrowRecords(ctx context.Context, brokerAddress string, query *Request) (*ArrowRecordReader, error)
+}
+
+// ArrowRecordReader is an array.RecordReader over the Arrow record batches sent by the broker for a query,
+// as returned by Connection.QueryArrow. Record batches are received one response block at a time,
+// as they are consumed, and are handed out without converting them to rows.
+//
+// As for any Arrow record reader, the record returned by Record is only valid until the next call to Next,
+// unless it is retained. The reader is reference counted: the RPC is torn down once its reference count
+// drops to zero, so Release must be called once done with it:
+//
+//	reader, err := conn.QueryArrow(ctx, "baseballStats", "select playerName, homeRuns from baseballStats")
+//	if err != nil {
+//		return err
+//	}
+//	defer reader.Release()
+//	for reader.Next() {
+//		record := reader.Record()
+//		...
+//	}
+//	return reader.Err()
+type ArrowRecordReader struct {
+	refCount    atomic.Int64
+	response    *BrokerResponse
+	pinotSchema RespSchema
+	allocator   memory.Allocator
+	nextPayload func() ([]byte, error)
+	closeFn     func() error
+	onDone      func(err error)
+	// clientQueryID identifies the query for Connection.Cancel
+	clientQueryID string
+
+	schema     *arrow.Schema
+	block      *ipc.Reader
+	pending    *ipc.Reader
+	pendingErr error
+	record     arrow.Record
+	err        error
+	done       bool
+	closeOnce  sync.Once
+}
+
+// newArrowRecordReader returns a reader over the Arrow IPC stream payloads returned by nextPayload
+// until it returns io.EOF. closeFn releases the resources of the reader, such as the underlying RPC.
+func newArrowRecordReader(response *BrokerResponse, pinotSchema RespSchema, allocator memory.Allocator,
+	nextPayload func() ([]byte, error), closeFn func() error) *ArrowRecordReader {
+	reader := &ArrowRecordReader{
+		response:    response,
+		pinotSchema: pinotSchema,
+		allocator:   allocator,
+		nextPayload: nextPayload,
+		closeFn:     closeFn,
+	}
+	reader.refCount.Store(1)
+	return reader
+}
+
+// ClientQueryID returns the client query ID of the query, to cancel it with Connection.Cancel.
+func (r *ArrowRecordReader) ClientQueryID() string {
+	return r.clientQueryID
+}
+
+// Metadata returns the broker response received before the records: query statistics and exceptions
+// are set, and its ResultTable, if any, holds the Pinot schema of the result but no rows.
+func (r *ArrowRecordReader) Metadata() *BrokerResponse {
+	return r.response
+}
+
+// Retain increases the reference count of the reader by 1.
+func (r *ArrowRecordReader) Retain() {
+	r.refCount.Add(1)
+}
+
+// Release decreases the reference count of the reader by 1. When it reaches 0, the current record
+// is released and the RPC is cancelled if records are left unread. Retained records stay valid.
+func (r *ArrowRecordReader) Release() {
+	if r.refCount.Add(-1) == 0 {
+		r.finish(nil)
+	}
+}
+
+// Schema returns the Arrow schema of the records. It receives the first record batch from the broker
+// when needed; when the result has no record batch, the schema is derived from the Pinot schema.
+func (r *ArrowRecordReader) Schema() *arrow.Schema {
+	if r.schema == nil && !r.done && r.pending == nil && r.pendingErr == nil {
+		r.pending, r.pendingErr = r.readBlock()
+	}
+	if r.schema == nil {
+		r.schema = arrowSchemaOf(r.pinotSchema)
+	}
+	return r.schema
+}
+
+// Next advances the reader to the next record, receiving the next block from the broker when needed.
+// It returns false once all records were read, an error occurred, or the reader was released.
+func (r *ArrowRecordReader) Next() bool {
+	if r.done {
+		return false
+	}
+	r.record = nil
+	for {
+		if r.block == nil {
+			block, err := r.takeBlock()
+			if err == io.EOF {
+				r.finish(nil)
+				return false
+			}
+			if err != nil {
+				r.err = err
+				r.finish(err)
+				return false
+			}
+			r.block = block
+		}
+		if r.block.Next() {
+			r.record = r.block.Record()
+			return true
+		}
+		if err := r.block.Err(); err != nil {
+			r.err = fmt.Errorf("failed to read arrow payload: %w", err)
+			r.finish(r.err)
+			return false
+		}
+		r.block.Release()
+		r.block = nil
+	}
+}
+
+// Record returns the current record. It is only valid until the next call to Next, unless retained.
+func (r *ArrowRecordReader) Record() arrow.Record {
+	return r.record
+}
+
+// Err returns the error that ended the iteration, or the error releasing the reader, nil when all
+// records were read.
+func (r *ArrowRecordReader) Err() error {
+	return r.err
+}
+
+// takeBlock returns the block read ahead by Schema, if any, or reads the next one.
+func (r *ArrowRecordReader) takeBlock() (*ipc.Reader, error) {
+	if r.pending != nil || r.pendingErr != nil {
+		block, err := r.pending, r.pendingErr
+		r.pending, r.pendingErr = nil, nil
+		return block, err
+	}
+	return r.readBlock()
+}
+
+// readBlock receives the next response block and opens the Arrow IPC stream it holds.
+func (r *ArrowRecordReader) readBlock() (*ipc.Reader, error) {
+	payload, err := r.nextPayload()
+	if err != nil {
+		return nil, err
+	}
+	block, err := ipc.NewReader(bytes.NewReader(payload), ipc.WithAllocator(r.allocator))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read arrow payload: %w", err)
+	}
+	if r.schema == nil {
+		r.schema = block.Schema()
+	}
+	return block, nil
+}
+
+func (r *ArrowRecordReader) setOnDone(onDone func(err error)) {
+	r.onDone = onDone
+}
+
+func (r *ArrowRecordReader) close() error {
+	r.finish(nil)
+	return r.err
+}
+
+// finish releases the reader once, reporting its outcome to onDone.
+func (r *ArrowRecordReader) finish(err error) {
+	r.done = true
+	r.record = nil
+	r.closeOnce.Do(func() {
+		for _, block := range []*ipc.Reader{r.block, r.pending} {
+			if block != nil {
+				block.Release()
+			}
+		}
+		r.block, r.pending = nil, nil
+		if closeErr := r.closeFn(); closeErr != nil && r.err == nil {
+			r.err = fmt.Errorf("failed to close arrow reader: %w", closeErr)
+		}
+		if r.onDone != nil {
+			r.onDone(err)
+		}
+	})
+}
+
+// arrowSchemaOf returns the Arrow schema the broker uses to encode results with the given Pinot schema.
+func arrowSchemaOf(schema RespSchema) *arrow.Schema {
+	fields := make([]arrow.Field, len(schema.ColumnNames))
+	for i, name := range schema.ColumnNames {
+		var columnType string
+		if i < len(schema.ColumnDataTypes) {
+			columnType = schema.ColumnDataTypes[i]
+		}
+		fields[i] = arrow.Field{Name: name, Type: arrowTypeOf(columnType), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+func arrowTypeOf(columnType string) arrow.DataType {
+	switch strings.ToUpper(columnType) {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "INT":
+		return arrow.PrimitiveTypes.Int32
+	case "LONG":
+		return arrow.PrimitiveTypes.Int64
+	case "FLOAT":
+		return arrow.PrimitiveTypes.Float32
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64
+	case "MAP":
+		return arrow.BinaryTypes.Binary
+	case "UNKNOWN":
+		return arrow.Null
+	case "BOOLEAN_ARRAY":
+		return arrow.ListOf(arrow.FixedWidthTypes.Boolean)
+	case "INT_ARRAY":
+		return arrow.ListOf(arrow.PrimitiveTypes.Int32)
+	case "LONG_ARRAY":
+		return arrow.ListOf(arrow.PrimitiveTypes.Int64)
+	case "FLOAT_ARRAY":
+		return arrow.ListOf(arrow.PrimitiveTypes.Float32)
+	case "DOUBLE_ARRAY":
+		return arrow.ListOf(arrow.PrimitiveTypes.Float64)
+	case "TIMESTAMP_ARRAY", "STRING_ARRAY", "BYTES_ARRAY":
+		return arrow.ListOf(arrow.BinaryTypes.String)
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
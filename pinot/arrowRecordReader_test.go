@@ -0,0 +1,162 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/startreedata/pinot-client-go/pinot/proto"
+)
+
+func arrowRowBlock(t *testing.T, values []int64) *proto.BrokerResponse {
+	return &proto.BrokerResponse{
+		Payload:  encodeArrowRowBlock(t, values),
+		Metadata: map[string]string{"encoding": "ARROW", "compression": "NONE", "rowSize": strconv.Itoa(len(values))},
+	}
+}
+
+func newArrowTestConnection(t *testing.T, address string, allocator memory.Allocator) *Connection {
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{address},
+		GrpcConfig: &GrpcConfig{Encoding: "ARROW", Compression: "NONE", ArrowAllocator: allocator},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestQueryArrow(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[],"numDocsScanned":5}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		arrowRowBlock(t, []int64{1, 2, 3}),
+		arrowRowBlock(t, []int64{4, 5}),
+	})
+	defer server.Stop()
+	allocator := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer allocator.AssertSize(t, 0)
+	conn := newArrowTestConnection(t, listener.Addr().String(), allocator)
+
+	reader, err := conn.QueryArrow(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), reader.Metadata().NumDocsScanned)
+	require.Equal(t, 1, reader.Schema().NumFields())
+	assert.Equal(t, "id", reader.Schema().Field(0).Name)
+	assert.Equal(t, arrow.PrimitiveTypes.Int64, reader.Schema().Field(0).Type)
+
+	var ids []int64
+	var retained []arrow.Record
+	for reader.Next() {
+		record := reader.Record()
+		ids = append(ids, record.Column(0).(*array.Int64).Int64Values()...)
+		record.Retain()
+		retained = append(retained, record)
+	}
+	require.NoError(t, reader.Err())
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+	assert.Nil(t, reader.Record())
+	reader.Release()
+
+	// Retained records outlive the reader.
+	require.Len(t, retained, 2)
+	assert.Equal(t, []int64{4, 5}, retained[1].Column(0).(*array.Int64).Int64Values())
+	for _, record := range retained {
+		record.Release()
+	}
+}
+
+func TestQueryArrowReleaseCancelsRPC(t *testing.T) {
+	server, listener, endless := startEndlessGrpcTestServer(t)
+	endless.block = arrowRowBlock(t, []int64{1})
+	defer server.Stop()
+	allocator := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	conn := newArrowTestConnection(t, listener.Addr().String(), allocator)
+
+	reader, err := conn.QueryArrow(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	reader.Retain()
+	for i := 0; i < 3; i++ {
+		require.True(t, reader.Next())
+	}
+	reader.Release()
+	assert.True(t, reader.Next())
+	reader.Release()
+	select {
+	case err := <-endless.finished:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the RPC was not cancelled by Release")
+	}
+	assert.False(t, reader.Next())
+	assert.NoError(t, reader.Err())
+	allocator.AssertSize(t, 0)
+}
+
+func TestArrowRecordReaderCloseError(t *testing.T) {
+	closeErr := errors.New("close failed")
+	reader := newArrowRecordReader(&BrokerResponse{}, RespSchema{}, memory.NewGoAllocator(),
+		func() ([]byte, error) { return nil, io.EOF }, func() error { return closeErr })
+	assert.False(t, reader.Next())
+	assert.ErrorIs(t, reader.Err(), closeErr)
+	reader.Release()
+}
+
+func TestQueryArrowSchemaWithoutRecords(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[]}`)},
+		{Payload: encodeTestSchema(t, []string{"id", "names"}, []string{"INT", "STRING_ARRAY"})},
+	})
+	defer server.Stop()
+	conn := newArrowTestConnection(t, listener.Addr().String(), nil)
+
+	reader, err := conn.QueryArrow(context.Background(), "", "select id, names from t")
+	require.NoError(t, err)
+	defer reader.Release()
+	schema := reader.Schema()
+	require.Equal(t, 2, schema.NumFields())
+	assert.Equal(t, arrow.PrimitiveTypes.Int32, schema.Field(0).Type)
+	assert.True(t, arrow.TypeEqual(arrow.ListOf(arrow.BinaryTypes.String), schema.Field(1).Type))
+	assert.False(t, reader.Next())
+	assert.NoError(t, reader.Err())
+}
+
+func TestQueryArrowUnexpectedEncoding(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[]}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		jsonRowBlock(t, [][]interface{}{{1}}),
+	})
+	defer server.Stop()
+	conn := newArrowTestConnection(t, listener.Addr().String(), nil)
+
+	reader, err := conn.QueryArrow(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	defer reader.Release()
+	assert.False(t, reader.Next())
+	assert.ErrorIs(t, reader.Err(), ErrArrowUnsupported)
+}
+
+func TestQueryArrowUnsupported(t *testing.T) {
+	conn, err := NewFromBrokerList([]string{"localhost:8000"})
+	require.NoError(t, err)
+	_, err = conn.QueryArrow(context.Background(), "", "select 1")
+	assert.ErrorIs(t, err, ErrArrowUnsupported)
+
+	conn, err = NewWithConfig(&ClientConfig{
+		BrokerList: []string{"localhost:8010"},
+		GrpcConfig: &GrpcConfig{Encoding: "JSON"},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.QueryArrow(context.Background(), "", "select 1")
+	assert.ErrorIs(t, err, ErrArrowUnsupported)
+}
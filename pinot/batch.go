@@ -0,0 +1,137 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// defaultBatchConcurrency is the number of queries of a batch running at once, unless configured.
+const defaultBatchConcurrency = 8
+
+// QuerySpec is a query of a batch executed by Connection.ExecuteBatch.
+type QuerySpec struct {
+	Table string
+	Query string
+	// Params replace the '?' placeholders of Query, as with ExecuteSQLWithParams.
+	Params []interface{}
+	// Options are merged with the query options of the connection, as with ExecuteSQLWithOptions.
+	Options *QueryOptions
+}
+
+// QueryResult is the outcome of a query executed by ExecuteAsync or ExecuteBatch.
+type QueryResult struct {
+	Response *BrokerResponse
+	Err      error
+}
+
+// BatchOption configures Connection.ExecuteBatch.
+type BatchOption func(*batchSettings)
+
+type batchSettings struct {
+	concurrency int
+	failFast    bool
+}
+
+// WithBatchConcurrency limits the number of queries of the batch running at once, 8 by default.
+func WithBatchConcurrency(concurrency int) BatchOption {
+	return func(s *batchSettings) {
+		if concurrency > 0 {
+			s.concurrency = concurrency
+		}
+	}
+}
+
+// WithFailFast makes the batch stop at the first failed query: queries still running are cancelled
+// and queries not started yet fail with context.Canceled.
+func WithFailFast() BatchOption {
+	return func(s *batchSettings) {
+		s.failFast = true
+	}
+}
+
+// ExecuteAsync executes an SQL query for a given table in the background. The returned channel receives
+// the outcome of the query once done, and is then closed. Cancelling ctx aborts the query.
+func (c *Connection) ExecuteAsync(ctx context.Context, table string, query string) <-chan QueryResult {
+	result := make(chan QueryResult, 1)
+	go func() {
+		defer close(result)
+		resp, err := c.ExecuteSQLContext(ctx, table, query)
+		result <- QueryResult{Response: resp, Err: err}
+	}()
+	return result
+}
+
+// ExecuteBatch executes independent queries concurrently and returns their results in the order of specs.
+// By default every query runs to completion and the returned error joins the errors of the failed queries,
+// each prefixed with its index; with WithFailFast, the first error cancels the rest of the batch and is
+// returned alone. The results of queries that succeeded are available in both cases.
+func (c *Connection) ExecuteBatch(ctx context.Context, specs []QuerySpec, opts ...BatchOption) ([]QueryResult, error) {
+	settings := batchSettings{concurrency: defaultBatchConcurrency}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]QueryResult, len(specs))
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, settings.concurrency)
+	for i := range specs {
+		select {
+		case slots <- struct{}{}:
+		case <-batchCtx.Done():
+			results[i].Err = batchCtx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			resp, err := c.executeSpec(batchCtx, specs[i])
+			results[i] = QueryResult{Response: resp, Err: err}
+			if err != nil && settings.failFast {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("query %d: %w", i, err)
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if settings.failFast {
+		if firstErr == nil && ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		return results, firstErr
+	}
+	var errs []error
+	for i, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("query %d: %w", i, result.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (c *Connection) executeSpec(ctx context.Context, spec QuerySpec) (*BrokerResponse, error) {
+	query := spec.Query
+	if len(spec.Params) > 0 {
+		formatted, err := formatQuery(query, spec.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format query: %w", err)
+		}
+		query = formatted
+	}
+	return c.executeSQL(ctx, spec.Table, query, spec.Options)
+}
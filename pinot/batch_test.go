@@ -0,0 +1,137 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startBatchTestBroker answers "select N" queries with N, after a short delay, and fails "fail" queries.
+func startBatchTestBroker(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var running, maxRunning atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			previous := maxRunning.Load()
+			if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		query := request["sql"]
+		if query == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"resultTable":{"dataSchema":{"columnDataTypes":["LONG"],"columnNames":["n"]},"rows":[[%s]]},"exceptions":[]}`,
+			strings.TrimPrefix(query, "select "))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &maxRunning
+}
+
+func TestExecuteAsync(t *testing.T) {
+	ts, _ := startBatchTestBroker(t)
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	first := conn.ExecuteAsync(context.Background(), "", "select 1")
+	second := conn.ExecuteAsync(context.Background(), "", "select 2")
+	result := <-second
+	require.NoError(t, result.Err)
+	assert.Equal(t, int64(2), result.Response.ResultTable.GetLong(0, 0))
+	result = <-first
+	require.NoError(t, result.Err)
+	assert.Equal(t, int64(1), result.Response.ResultTable.GetLong(0, 0))
+	_, ok := <-first
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = <-conn.ExecuteAsync(ctx, "", "select 3")
+	assert.ErrorIs(t, result.Err, context.Canceled)
+}
+
+func TestExecuteBatch(t *testing.T) {
+	ts, maxRunning := startBatchTestBroker(t)
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	specs := make([]QuerySpec, 10)
+	for i := range specs {
+		specs[i] = QuerySpec{Query: "select ?", Params: []interface{}{i}}
+	}
+	results, err := conn.ExecuteBatch(context.Background(), specs, WithBatchConcurrency(3))
+	require.NoError(t, err)
+	require.Len(t, results, 10)
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		assert.Equal(t, int64(i), result.Response.ResultTable.GetLong(0, 0))
+	}
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+	assert.Greater(t, maxRunning.Load(), int32(1))
+}
+
+func TestExecuteBatchCollectAll(t *testing.T) {
+	ts, _ := startBatchTestBroker(t)
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	results, err := conn.ExecuteBatch(context.Background(), []QuerySpec{
+		{Query: "select 1"},
+		{Query: "fail"},
+		{Query: "select ?", Params: []interface{}{1, 2}},
+		{Query: "select 4"},
+	})
+	require.Len(t, results, 4)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[3].Err)
+	assert.Equal(t, int64(4), results[3].Response.ResultTable.GetLong(0, 0))
+	var httpErr *BrokerHTTPError
+	assert.ErrorAs(t, results[1].Err, &httpErr)
+	assert.ErrorContains(t, results[2].Err, "failed to format query")
+	assert.ErrorContains(t, err, "query 1: ")
+	assert.ErrorContains(t, err, "query 2: failed to format query")
+	assert.ErrorAs(t, err, &httpErr)
+}
+
+func TestExecuteBatchFailFast(t *testing.T) {
+	ts, _ := startBatchTestBroker(t)
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	specs := []QuerySpec{{Query: "fail"}}
+	for i := 0; i < 5; i++ {
+		specs = append(specs, QuerySpec{Query: fmt.Sprintf("select %d", i)})
+	}
+	results, err := conn.ExecuteBatch(context.Background(), specs, WithFailFast(), WithBatchConcurrency(1))
+	require.Len(t, results, 6)
+	var httpErr *BrokerHTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.ErrorContains(t, err, "query 0: ")
+	for _, result := range results[1:] {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+	}
+
+	results, err = conn.ExecuteBatch(context.Background(), nil, WithFailFast())
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
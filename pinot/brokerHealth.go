@@ -0,0 +1,386 @@
+package pinot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitConsecutiveFailures = 5
+	defaultCircuitMinRequests         = 20
+	defaultCircuitWindow              = 30 * time.Second
+	defaultCircuitOpenDuration        = 30 * time.Second
+	defaultCircuitHalfOpenRequests    = 1
+	defaultHealthCheckTimeout         = 2 * time.Second
+)
+
+// CircuitState is the state of the circuit breaker of a broker.
+type CircuitState int
+
+const (
+	// CircuitClosed brokers receive queries normally
+	CircuitClosed CircuitState = iota
+	// CircuitOpen brokers are ejected and receive no queries until CircuitBreakerConfig.OpenDuration elapsed
+	CircuitOpen
+	// CircuitHalfOpen brokers receive a limited number of trial queries deciding whether the circuit closes again
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreakerConfig configures the health tracking of brokers. Brokers whose queries keep failing
+// are ejected for a while, then receive trial queries before being used normally again.
+// Connection errors, 5xx responses, transport timeouts and throttling count as failures,
+// while client errors and queries cancelled by the caller are ignored.
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures opens the circuit of a broker after this many failures in a row - defaults to 5
+	ConsecutiveFailures int
+	// FailureRateThreshold opens the circuit of a broker when the rate of failed queries over Window
+	// reaches it, between 0 and 1. The failure rate is not considered when it is 0.
+	FailureRateThreshold float64
+	// MinRequests is the number of queries within Window required before the failure rate is considered - defaults to 20
+	MinRequests int
+	// Window is the period over which the failure rate is computed - defaults to 30s
+	Window time.Duration
+	// OpenDuration is how long a broker is ejected before trial queries are sent to it - defaults to 30s
+	OpenDuration time.Duration
+	// HalfOpenRequests is the number of successful trial queries closing the circuit again - defaults to 1
+	HalfOpenRequests int
+	// HealthCheckInterval enables active probing of the /health endpoint of every known broker
+	// at this interval, e.g. for static BrokerList setups. Probes count as queries. 0 disables probing.
+	// Probing uses HTTP and ExtraHTTPHeader, so it does not apply to brokers queried over gRPC.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout is the timeout of each /health probe - defaults to 2s
+	HealthCheckTimeout time.Duration
+}
+
+// BrokerHealth is a snapshot of the health of a broker, as returned by Connection.BrokerHealth.
+type BrokerHealth struct {
+	// Broker is the address of the broker
+	Broker string
+	// State is the state of the circuit breaker of the broker
+	State CircuitState
+	// ConsecutiveFailures is the number of queries that failed in a row
+	ConsecutiveFailures int
+	// Requests is the number of queries completed within the current window
+	Requests int
+	// Failures is the number of queries failed within the current window
+	Failures int
+	// LastError is the error of the last failed query, nil when no query failed yet
+	LastError error
+	// LastFailure is the time of the last failed query
+	LastFailure time.Time
+	// OpenUntil is the time an open circuit becomes half-open
+	OpenUntil time.Time
+}
+
+// brokerCircuit is the circuit breaker of a broker, guarded by brokerHealthTracker.mu.
+type brokerCircuit struct {
+	state               CircuitState
+	consecutiveFailures int
+	windowStart         time.Time
+	requests            int
+	failures            int
+	lastError           error
+	lastFailure         time.Time
+	openUntil           time.Time
+	trialsInFlight      int
+	trialSuccesses      int
+}
+
+// brokerHealthTracker tracks the health of brokers from query outcomes and optional /health probes.
+type brokerHealthTracker struct {
+	config   CircuitBreakerConfig
+	mu       sync.Mutex
+	circuits map[string]*brokerCircuit
+	now      func() time.Time
+	logger   Logger
+
+	// probing
+	selector brokerSelector
+	client   HTTPClient
+	header   map[string]string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newBrokerHealthTracker(config *CircuitBreakerConfig) *brokerHealthTracker {
+	c := *config
+	if c.ConsecutiveFailures <= 0 {
+		c.ConsecutiveFailures = defaultCircuitConsecutiveFailures
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = defaultCircuitMinRequests
+	}
+	if c.Window <= 0 {
+		c.Window = defaultCircuitWindow
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = defaultCircuitOpenDuration
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = defaultCircuitHalfOpenRequests
+	}
+	if c.HealthCheckTimeout <= 0 {
+		c.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &brokerHealthTracker{
+		config:   c,
+		circuits: make(map[string]*brokerCircuit),
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// circuit returns the circuit of broker, moving it to half-open once its open duration elapsed.
+// The caller must hold h.mu.
+func (h *brokerHealthTracker) circuit(broker string, now time.Time) *brokerCircuit {
+	c, ok := h.circuits[broker]
+	if !ok {
+		c = &brokerCircuit{windowStart: now}
+		h.circuits[broker] = c
+	}
+	if c.state == CircuitOpen && !now.Before(c.openUntil) {
+		c.state = CircuitHalfOpen
+		c.trialsInFlight = 0
+		c.trialSuccesses = 0
+	}
+	return c
+}
+
+// filter returns the brokers that can receive a query, or all of them when every broker is ejected,
+// so that queries still have a chance to succeed rather than failing right away.
+func (h *brokerHealthTracker) filter(brokers []string) []string {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	healthy := make([]string, 0, len(brokers))
+	for _, broker := range brokers {
+		c := h.circuit(broker, now)
+		switch c.state {
+		case CircuitClosed:
+			healthy = append(healthy, broker)
+		case CircuitHalfOpen:
+			if c.trialsInFlight+c.trialSuccesses < h.config.HalfOpenRequests {
+				healthy = append(healthy, broker)
+			}
+		}
+	}
+	if len(healthy) == 0 {
+		return brokers
+	}
+	return healthy
+}
+
+// begin records a query sent to broker.
+func (h *brokerHealthTracker) begin(broker string) {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c := h.circuit(broker, now); c.state == CircuitHalfOpen {
+		c.trialsInFlight++
+	}
+}
+
+// end records the outcome of a query sent to broker. Errors that do not tell about the health
+// of the broker, such as client errors or cancellation by the caller, are ignored.
+func (h *brokerHealthTracker) end(ctx context.Context, broker string, err error) {
+	switch {
+	case err == nil:
+		h.record(broker, nil)
+	case ctx.Err() == nil && classifyError(err) != 0:
+		h.record(broker, err)
+	default:
+		h.release(broker)
+	}
+}
+
+// release ends a query without recording its outcome.
+func (h *brokerHealthTracker) release(broker string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c, ok := h.circuits[broker]; ok && c.state == CircuitHalfOpen && c.trialsInFlight > 0 {
+		c.trialsInFlight--
+	}
+}
+
+// record updates the circuit of broker with the outcome of a query, err being nil on success.
+func (h *brokerHealthTracker) record(broker string, err error) {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := h.circuit(broker, now)
+	if now.Sub(c.windowStart) >= h.config.Window {
+		c.windowStart = now
+		c.requests = 0
+		c.failures = 0
+	}
+	c.requests++
+	if c.state == CircuitHalfOpen && c.trialsInFlight > 0 {
+		c.trialsInFlight--
+	}
+	if err == nil {
+		c.consecutiveFailures = 0
+		if c.state == CircuitHalfOpen {
+			c.trialSuccesses++
+			if c.trialSuccesses >= h.config.HalfOpenRequests {
+				loggerOrDefault(h.logger).Info(context.Background(), "Broker recovered, closing its circuit", "broker", broker)
+				c.state = CircuitClosed
+				c.windowStart = now
+				c.requests = 0
+				c.failures = 0
+			}
+		}
+		return
+	}
+	c.failures++
+	c.consecutiveFailures++
+	c.lastError = err
+	c.lastFailure = now
+	switch c.state {
+	case CircuitHalfOpen:
+		h.open(broker, c, now)
+	case CircuitClosed:
+		if c.consecutiveFailures >= h.config.ConsecutiveFailures ||
+			(h.config.FailureRateThreshold > 0 && c.requests >= h.config.MinRequests &&
+				float64(c.failures)/float64(c.requests) >= h.config.FailureRateThreshold) {
+			h.open(broker, c, now)
+		}
+	}
+}
+
+// open ejects the broker for the configured open duration. The caller must hold h.mu.
+func (h *brokerHealthTracker) open(broker string, c *brokerCircuit, now time.Time) {
+	loggerOrDefault(h.logger).Warn(context.Background(), "Ejecting broker", "broker", broker,
+		"openDuration", h.config.OpenDuration, "failures", c.failures, "requests", c.requests, "error", c.lastError)
+	c.state = CircuitOpen
+	c.openUntil = now.Add(h.config.OpenDuration)
+	c.trialsInFlight = 0
+	c.trialSuccesses = 0
+}
+
+// snapshot returns the health of every tracked broker, sorted by address.
+func (h *brokerHealthTracker) snapshot() []BrokerHealth {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	health := make([]BrokerHealth, 0, len(h.circuits))
+	for broker := range h.circuits {
+		c := h.circuit(broker, now)
+		health = append(health, BrokerHealth{
+			Broker:              broker,
+			State:               c.state,
+			ConsecutiveFailures: c.consecutiveFailures,
+			Requests:            c.requests,
+			Failures:            c.failures,
+			LastError:           c.lastError,
+			LastFailure:         c.lastFailure,
+			OpenUntil:           c.openUntil,
+		})
+	}
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Broker < health[j].Broker
+	})
+	return health
+}
+
+// startProbing probes the /health endpoint of the brokers known to selector in the background,
+// until close is called. It is a no-op when probing is not enabled.
+func (h *brokerHealthTracker) startProbing(selector brokerSelector, client HTTPClient, header map[string]string) {
+	if h.config.HealthCheckInterval <= 0 {
+		return
+	}
+	h.selector = selector
+	h.client = client
+	h.header = header
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.config.HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.ctx.Done():
+				return
+			case <-ticker.C:
+				h.probeAll()
+			}
+		}
+	}()
+}
+
+// probeAll probes every broker known to the selector once.
+func (h *brokerHealthTracker) probeAll() {
+	brokers, err := h.selector.availableBrokers("")
+	if err != nil {
+		return
+	}
+	seen := make(map[string]bool, len(brokers))
+	for _, broker := range brokers {
+		if seen[broker] || h.ctx.Err() != nil {
+			continue
+		}
+		seen[broker] = true
+		err := h.probe(broker)
+		if h.ctx.Err() != nil {
+			// The probe was aborted by close, which says nothing of the broker.
+			return
+		}
+		h.record(broker, err)
+	}
+}
+
+// probe sends a GET request to the /health endpoint of broker, returning nil when it answered 200.
+func (h *brokerHealthTracker) probe(broker string) error {
+	// Closing the tracker aborts in-flight probes.
+	ctx, cancel := context.WithTimeout(h.ctx, h.config.HealthCheckTimeout)
+	defer cancel()
+	url := "http://" + broker + "/health"
+	if strings.HasPrefix(broker, "http://") || strings.HasPrefix(broker, "https://") {
+		url = broker + "/health"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid health check request: %w", err)
+	}
+	for k, v := range h.header {
+		req.Header.Add(k, v)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			loggerOrDefault(h.logger).Error(ctx, "Got exceptions during closing health check response body", "error", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return &BrokerHTTPError{Broker: broker, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// close stops probing and waits for the probing goroutine to exit.
+func (h *brokerHealthTracker) close() {
+	h.cancel()
+	h.wg.Wait()
+}
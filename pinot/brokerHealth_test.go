@@ -0,0 +1,253 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBrokerDown = &BrokerHTTPError{StatusCode: http.StatusServiceUnavailable}
+
+func newTestHealthTracker(config *CircuitBreakerConfig) (*brokerHealthTracker, *time.Time) {
+	tracker := newBrokerHealthTracker(config)
+	now := time.Unix(1000, 0)
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func TestCircuitStateString(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+	assert.Equal(t, "CircuitState(7)", CircuitState(7).String())
+}
+
+func TestCircuitOpensOnConsecutiveFailures(t *testing.T) {
+	tracker, now := newTestHealthTracker(&CircuitBreakerConfig{ConsecutiveFailures: 3, OpenDuration: 10 * time.Second})
+	brokers := []string{"b1", "b2"}
+
+	tracker.record("b1", errBrokerDown)
+	tracker.record("b1", errBrokerDown)
+	tracker.record("b1", nil)
+	tracker.record("b1", errBrokerDown)
+	tracker.record("b1", errBrokerDown)
+	assert.Equal(t, brokers, tracker.filter(brokers))
+
+	tracker.record("b1", errBrokerDown)
+	assert.Equal(t, []string{"b2"}, tracker.filter(brokers))
+	health := tracker.snapshot()
+	require.Len(t, health, 2)
+	assert.Equal(t, "b1", health[0].Broker)
+	assert.Equal(t, CircuitClosed, health[1].State)
+	assert.Equal(t, CircuitOpen, health[0].State)
+	assert.Equal(t, 3, health[0].ConsecutiveFailures)
+	assert.Equal(t, 6, health[0].Requests)
+	assert.Equal(t, 5, health[0].Failures)
+	assert.Equal(t, errBrokerDown, health[0].LastError)
+	assert.Equal(t, now.Add(10*time.Second), health[0].OpenUntil)
+
+	// Every broker ejected: all of them are returned rather than none.
+	assert.Equal(t, []string{"b1"}, tracker.filter([]string{"b1"}))
+}
+
+func TestCircuitHalfOpen(t *testing.T) {
+	tracker, now := newTestHealthTracker(&CircuitBreakerConfig{ConsecutiveFailures: 1, OpenDuration: 10 * time.Second, HalfOpenRequests: 2})
+	brokers := []string{"b1", "b2"}
+	tracker.record("b1", errBrokerDown)
+	assert.Equal(t, []string{"b2"}, tracker.filter(brokers))
+
+	*now = now.Add(10 * time.Second)
+	assert.Equal(t, brokers, tracker.filter(brokers))
+	assert.Equal(t, CircuitHalfOpen, tracker.snapshot()[0].State)
+
+	// Trial queries are limited to HalfOpenRequests at a time.
+	tracker.begin("b1")
+	tracker.begin("b1")
+	assert.Equal(t, []string{"b2"}, tracker.filter(brokers))
+	tracker.end(context.Background(), "b1", nil)
+	assert.Equal(t, CircuitHalfOpen, tracker.snapshot()[0].State)
+	tracker.end(context.Background(), "b1", nil)
+	assert.Equal(t, CircuitClosed, tracker.snapshot()[0].State)
+
+	// A failed trial query opens the circuit again.
+	tracker.record("b1", errBrokerDown)
+	*now = now.Add(10 * time.Second)
+	tracker.begin("b1")
+	tracker.end(context.Background(), "b1", errBrokerDown)
+	assert.Equal(t, CircuitOpen, tracker.snapshot()[0].State)
+	assert.Equal(t, []string{"b2"}, tracker.filter(brokers))
+}
+
+func TestCircuitOpensOnFailureRate(t *testing.T) {
+	tracker, now := newTestHealthTracker(&CircuitBreakerConfig{
+		ConsecutiveFailures:  100,
+		FailureRateThreshold: 0.5,
+		MinRequests:          10,
+		Window:               time.Minute,
+	})
+	for i := 0; i < 8; i++ {
+		tracker.record("b1", nil)
+		tracker.record("b1", errBrokerDown)
+	}
+	// 8 failures out of 16 queries.
+	assert.Equal(t, CircuitOpen, tracker.snapshot()[0].State)
+
+	tracker, now = newTestHealthTracker(&CircuitBreakerConfig{
+		ConsecutiveFailures:  100,
+		FailureRateThreshold: 0.5,
+		MinRequests:          10,
+		Window:               time.Minute,
+	})
+	for i := 0; i < 4; i++ {
+		tracker.record("b1", nil)
+		tracker.record("b1", errBrokerDown)
+	}
+	// The window is reset before MinRequests is reached.
+	*now = now.Add(time.Minute)
+	for i := 0; i < 4; i++ {
+		tracker.record("b1", nil)
+		tracker.record("b1", errBrokerDown)
+	}
+	assert.Equal(t, CircuitClosed, tracker.snapshot()[0].State)
+	assert.Equal(t, 8, tracker.snapshot()[0].Requests)
+}
+
+func TestHealthTrackerIgnoresClientErrors(t *testing.T) {
+	tracker, _ := newTestHealthTracker(&CircuitBreakerConfig{ConsecutiveFailures: 1})
+	tracker.begin("b1")
+	tracker.end(context.Background(), "b1", &BrokerHTTPError{StatusCode: http.StatusBadRequest})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tracker.begin("b1")
+	tracker.end(ctx, "b1", context.Canceled)
+	assert.Equal(t, CircuitClosed, tracker.snapshot()[0].State)
+	assert.Equal(t, 0, tracker.snapshot()[0].Failures)
+}
+
+func TestConnectionEjectsFailingBroker(t *testing.T) {
+	var failingCalls, healthyCalls atomic.Int32
+	failing := newCountingBroker(t, http.StatusServiceUnavailable, &failingCalls)
+	defer failing.Close()
+	healthy := newCountingBroker(t, http.StatusOK, &healthyCalls)
+	defer healthy.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:     []string{failing.URL, healthy.URL},
+		LoadBalancer:   NewRoundRobinLoadBalancer(),
+		CircuitBreaker: &CircuitBreakerConfig{ConsecutiveFailures: 2, OpenDuration: time.Minute},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Nil(t, (&Connection{}).BrokerHealth())
+
+	for i := 0; i < 20; i++ {
+		_, _ = conn.ExecuteSQL("", "select 1")
+	}
+	assert.Equal(t, int32(2), failingCalls.Load())
+	assert.Equal(t, int32(18), healthyCalls.Load())
+
+	health := conn.With(WithTrace()).BrokerHealth()
+	require.Len(t, health, 2)
+	states := map[string]CircuitState{health[0].Broker: health[0].State, health[1].Broker: health[1].State}
+	assert.Equal(t, CircuitOpen, states[failing.URL])
+	assert.Equal(t, CircuitClosed, states[healthy.URL])
+}
+
+func TestHealthProbing(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	var probes atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" || r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		probes.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, err := w.Write([]byte("OK"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:      []string{ts.URL},
+		ExtraHTTPHeader: map[string]string{"Authorization": "Bearer token"},
+		CircuitBreaker: &CircuitBreakerConfig{
+			ConsecutiveFailures: 1,
+			OpenDuration:        time.Hour,
+			HealthCheckInterval: 10 * time.Millisecond,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		health := conn.BrokerHealth()
+		return len(health) == 1 && health[0].Requests > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, CircuitClosed, conn.BrokerHealth()[0].State)
+
+	healthy.Store(false)
+	assert.Eventually(t, func() bool {
+		return conn.BrokerHealth()[0].State == CircuitOpen
+	}, 5*time.Second, 10*time.Millisecond)
+	var httpErr *BrokerHTTPError
+	assert.True(t, errors.As(conn.BrokerHealth()[0].LastError, &httpErr))
+
+	require.NoError(t, conn.Close())
+	// Let a probe cancelled by Close reach the server before counting.
+	time.Sleep(20 * time.Millisecond)
+	count := probes.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, count, probes.Load())
+}
+
+func TestHealthProbeAbortedByClose(t *testing.T) {
+	tracker := newBrokerHealthTracker(&CircuitBreakerConfig{ConsecutiveFailures: 1, HealthCheckInterval: time.Millisecond})
+	probing := make(chan struct{}, 1)
+	client := &recordingHTTPClient{do: func(req *http.Request) (*http.Response, error) {
+		select {
+		case probing <- struct{}{}:
+		default:
+		}
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}}
+	tracker.startProbing(&simpleBrokerSelector{brokerList: []string{"localhost:8000"}}, client, nil)
+
+	<-probing
+	tracker.close()
+	assert.Empty(t, tracker.snapshot())
+}
+
+func TestHealthProbeURL(t *testing.T) {
+	var requested string
+	tracker := newBrokerHealthTracker(&CircuitBreakerConfig{})
+	tracker.client = &recordingHTTPClient{do: func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}}
+	require.NoError(t, tracker.probe("localhost:8000"))
+	assert.Equal(t, "http://localhost:8000/health", requested)
+	require.NoError(t, tracker.probe("https://broker:8443"))
+	assert.True(t, strings.HasPrefix(requested, "https://broker:8443/health"))
+}
+
+type recordingHTTPClient struct {
+	do func(req *http.Request) (*http.Response, error)
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.do(req)
+}
@@ -1,8 +1,26 @@
 // Package pinot provides a client for Pinot, a real-time distributed OLAP datastore.
 package pinot
 
+import "io"
+
 type brokerSelector interface {
+	// Close stops any background broker discovery started by init
+	io.Closer
 	init() error
 	// Returns the broker address in the form host:port
 	selectBroker(table string) (string, error)
+	// Returns all the broker addresses able to serve the table
+	availableBrokers(table string) ([]string, error)
+}
+
+// BrokerSelectorObserver is notified of the broker lists discovered by the connection, see
+// ClientConfig.BrokerSelectorObserver. Its methods are called from the discovery goroutine and must not block.
+type BrokerSelectorObserver interface {
+	// BrokersUpdated is called with the brokers serving each table and the brokers of the cluster,
+	// once for a static BrokerList and after every refresh for Zookeeper and controller discovery.
+	// The arguments must not be modified.
+	BrokersUpdated(tableBrokers map[string][]string, allBrokers []string)
+	// BrokersRefreshFailed is called when the broker lists could not be refreshed from Zookeeper or
+	// the controller. The previous broker lists remain in use.
+	BrokersRefreshFailed(err error)
 }
@@ -0,0 +1,129 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// cancelTimeout bounds the cancellation of the queries abandoned by their caller.
+const cancelTimeout = 10 * time.Second
+
+// runningQuery is a query in flight, registered in Connection.running under its client query ID.
+type runningQuery struct {
+	brokerAddress string
+	// stop tears down the RPC of a streamed query, nil for other queries
+	stop context.CancelFunc
+}
+
+// requestSentKey is the context key of the flag set by transports once a request is sent to the broker.
+type requestSentKey struct{}
+
+// withRequestSent returns a context in which transports report that the request reached the broker,
+// so that only queries the broker received are cancelled.
+func withRequestSent(ctx context.Context) (context.Context, *atomic.Bool) {
+	sent := &atomic.Bool{}
+	return context.WithValue(ctx, requestSentKey{}, sent), sent
+}
+
+// traceRequestSent returns ctx with an HTTP client trace setting the flag of withRequestSent, if any,
+// once the request is written to the broker.
+func traceRequestSent(ctx context.Context) context.Context {
+	sent, ok := ctx.Value(requestSentKey{}).(*atomic.Bool)
+	if !ok {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				sent.Store(true)
+			}
+		},
+	})
+}
+
+// startRunning registers a streamed query as running on the broker until the returned function is called.
+// The returned context is the one of the RPC, cancelled by Cancel or by the returned function.
+func (c *Connection) startRunning(ctx context.Context, brokerAddress string, request *Request) (context.Context, func()) {
+	ctx, stop := context.WithCancel(ctx)
+	query := &runningQuery{brokerAddress: brokerAddress, stop: stop}
+	running := &c.root().running
+	running.Store(request.clientQueryID, query)
+	return ctx, func() {
+		running.CompareAndDelete(request.clientQueryID, query)
+		stop()
+	}
+}
+
+// cancelingTransport is implemented by transports able to cancel queries running on a broker.
+type cancelingTransport interface {
+	cancel(ctx context.Context, brokerAddress string, clientQueryID string) error
+}
+
+// Cancel cancels a running query by its client query ID, as set with QueryOptions.ClientQueryID or
+// returned by RowStream.ClientQueryID and ArrowRecordReader.ClientQueryID.
+// Queries issued through the connection are cancelled on the broker running them, other queries on
+// every broker, the error matching ErrQueryNotFound when no broker runs the query.
+// It requires the HTTP transport and returns ErrCancelUnsupported otherwise, except for the queries
+// streamed over gRPC through the connection, whose RPC is torn down.
+//
+// Queries are also cancelled automatically when the context passed to them is cancelled before the
+// response is received.
+func (c *Connection) Cancel(ctx context.Context, queryID string) error {
+	if c.root().closed.Load() {
+		return ErrConnectionClosed
+	}
+	transport, canCancel := c.transport.(cancelingTransport)
+	if value, ok := c.root().running.Load(queryID); ok {
+		if query, ok := value.(*runningQuery); ok {
+			if query.stop != nil {
+				// Streamed queries are cancelled by tearing down their RPC.
+				query.stop()
+				return nil
+			}
+			if canCancel {
+				return transport.cancel(ctx, query.brokerAddress, queryID)
+			}
+		}
+	}
+	if !canCancel {
+		return ErrCancelUnsupported
+	}
+	brokers, err := c.brokerSelector.availableBrokers("")
+	if err != nil {
+		return fmt.Errorf("unable to find the brokers to cancel query %s: %w", queryID, err)
+	}
+	var errs []error
+	for _, brokerAddress := range brokers {
+		err := transport.cancel(ctx, brokerAddress, queryID)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrQueryNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return fmt.Errorf("%w: %s", ErrQueryNotFound, queryID)
+}
+
+// cancelAbandoned cancels in the background a query whose caller gave up on, so that the broker
+// and servers stop spending resources on it.
+func (c *Connection) cancelAbandoned(brokerAddress string, clientQueryID string) {
+	transport, ok := c.transport.(cancelingTransport)
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+		defer cancel()
+		if err := transport.cancel(ctx, brokerAddress, clientQueryID); err != nil && !errors.Is(err, ErrQueryNotFound) {
+			loggerOrDefault(c.logger).Warn(ctx, "Failed to cancel query", "clientQueryId", clientQueryID, "broker", brokerAddress, "error", err)
+		}
+	}()
+}
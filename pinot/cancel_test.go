@@ -0,0 +1,191 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cancelTestBroker holds queries until they are cancelled through DELETE /query/{id}?client=true.
+type cancelTestBroker struct {
+	started   chan string
+	cancelled chan string
+	running   chan struct{}
+}
+
+func startCancelTestBroker(t *testing.T) (*httptest.Server, *cancelTestBroker) {
+	broker := &cancelTestBroker{
+		started:   make(chan string, 1),
+		cancelled: make(chan string, 1),
+		running:   make(chan struct{}),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			id := strings.TrimPrefix(r.URL.Path, "/query/")
+			if r.URL.Query().Get("client") != "true" || id == "unknown" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			broker.cancelled <- id
+			close(broker.running)
+			_, err := w.Write([]byte("Cancelled client query: " + id))
+			assert.NoError(t, err)
+			return
+		}
+		var request map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		_, id, _ := strings.Cut(request["queryOptions"], "clientQueryId=")
+		assert.Equal(t, id, r.Header.Get("X-Correlation-Id"))
+		broker.started <- id
+		select {
+		case <-broker.running:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[{"errorCode":503,"message":"QueryCancellationError"}]}`))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, broker
+}
+
+func TestCancel(t *testing.T) {
+	ts, broker := startCancelTestBroker(t)
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, queryErr := conn.ExecuteSQLWithOptions(context.Background(), "", "select 1", &QueryOptions{ClientQueryID: "my-query"})
+		done <- queryErr
+	}()
+	assert.Equal(t, "my-query", <-broker.started)
+	require.NoError(t, conn.Cancel(context.Background(), "my-query"))
+	assert.Equal(t, "my-query", <-broker.cancelled)
+	assert.NoError(t, <-done)
+
+	// Queries not issued through the connection are looked up on every broker.
+	err = conn.Cancel(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrQueryNotFound)
+}
+
+func TestCancelOnContextCancel(t *testing.T) {
+	ts, broker := startCancelTestBroker(t)
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.ExecuteSQLContext(ctx, "", "select 1")
+		done <- err
+	}()
+	id := <-broker.started
+	assert.Len(t, id, 36)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	select {
+	case cancelled := <-broker.cancelled:
+		assert.Equal(t, id, cancelled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query was not cancelled on the broker")
+	}
+}
+
+func TestCancelOnlySentQueries(t *testing.T) {
+	var deletes atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletes.Add(1)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{ts.URL},
+		Interceptors: []Interceptor{func(next Transport) Transport {
+			return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+				if request.SQL() == "rejected" {
+					return nil, ctx.Err()
+				}
+				return next.Execute(ctx, brokerAddress, request)
+			})
+		}},
+	})
+	require.NoError(t, err)
+
+	// Neither a query cancelled before being sent nor one rejected by an interceptor reach the broker.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = conn.ExecuteSQLContext(ctx, "", "select 1")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = conn.ExecuteSQLContext(ctx, "", "rejected")
+	assert.ErrorIs(t, err, context.Canceled)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), deletes.Load())
+}
+
+func TestCancelStreamGrpc(t *testing.T) {
+	server, listener, endless := startEndlessGrpcTestServer(t)
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	stream, err := conn.With(WithQueryOptions(&QueryOptions{ClientQueryID: "streamed"})).QueryStream(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, "streamed", stream.ClientQueryID())
+	require.True(t, stream.Next())
+
+	require.NoError(t, conn.Cancel(context.Background(), stream.ClientQueryID()))
+	select {
+	case err := <-endless.finished:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the RPC was not cancelled by Cancel")
+	}
+	for stream.Next() {
+	}
+	assert.ErrorIs(t, stream.Err(), context.Canceled)
+	// Streams are no longer running once done.
+	assert.ErrorIs(t, conn.Cancel(context.Background(), stream.ClientQueryID()), ErrCancelUnsupported)
+}
+
+func TestCancelErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+	var httpErr *BrokerHTTPError
+	require.ErrorAs(t, conn.Cancel(context.Background(), "q"), &httpErr)
+	assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+
+	grpcConn, err := NewWithConfig(&ClientConfig{BrokerList: []string{"localhost:8010"}, GrpcConfig: &GrpcConfig{}})
+	require.NoError(t, err)
+	defer grpcConn.Close()
+	assert.ErrorIs(t, grpcConn.Cancel(context.Background(), "q"), ErrCancelUnsupported)
+
+	require.NoError(t, conn.Close())
+	assert.ErrorIs(t, conn.Cancel(context.Background(), "q"), ErrConnectionClosed)
+}
+
+func TestNewRequestClientQueryID(t *testing.T) {
+	conn := &Connection{}
+	first, second := conn.newRequest("", "select 1", nil), conn.newRequest("", "select 1", nil)
+	assert.Len(t, first.clientQueryID, 36)
+	assert.NotEqual(t, first.clientQueryID, second.clientQueryID)
+	assert.Equal(t, "a", conn.newRequest("", "select 1", &QueryOptions{ClientQueryID: "a"}).clientQueryID)
+	request := conn.newRequest("", "select 1", &QueryOptions{ClientQueryID: "a", Extra: map[string]string{"clientQueryId": "b"}})
+	assert.Equal(t, "b", request.clientQueryID)
+	assert.Equal(t, "groupByMode=sql;responseFormat=sql;clientQueryId=b", buildQueryOptions(request, 0))
+}
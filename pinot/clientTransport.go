@@ -1,5 +1,94 @@
 package pinot
 
+import "context"
+
 type clientTransport interface {
-	execute(brokerAddress string, query *Request) (*BrokerResponse, error)
+	execute(ctx context.Context, brokerAddress string, query *Request) (*BrokerResponse, error)
+	// close releases resources held by the transport, such as idle network connections
+	close() error
+}
+
+// Transport executes a query on a broker. The transports of the client, HTTP and gRPC, are wrapped
+// by the interceptors of ClientConfig.Interceptors.
+type Transport interface {
+	Execute(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error)
+}
+
+// TransportFunc adapts a function to the Transport interface.
+type TransportFunc func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error)
+
+// Execute calls f.
+func (f TransportFunc) Execute(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+	return f(ctx, brokerAddress, request)
+}
+
+// Interceptor wraps the Transport executing queries, to add behavior around every query sent to a broker,
+// such as logging, metrics, header injection or query rewriting. It sees the broker address and the
+// request, and the response and error returned by next. A call to next is an attempt on a broker:
+// retried queries go through the interceptors again.
+//
+// For the queries streamed with Connection.QueryStream and Connection.QueryArrow, next returns once the
+// stream is open, with the metadata received before the results as the response. The context passed to
+// next must then remain valid until the stream is consumed.
+//
+//	func logQueries(next pinot.Transport) pinot.Transport {
+//		return pinot.TransportFunc(func(ctx context.Context, broker string, req *pinot.Request) (*pinot.BrokerResponse, error) {
+//			start := time.Now()
+//			resp, err := next.Execute(ctx, broker, req)
+//			log.Printf("%s on %s took %s: %v", req.SQL(), broker, time.Since(start), err)
+//			return resp, err
+//		})
+//	}
+type Interceptor func(next Transport) Transport
+
+// QueryFunc executes a query: it picks a broker for the query and retries it on other brokers
+// according to the retry policy.
+type QueryFunc func(ctx context.Context, request *Request) (*BrokerResponse, error)
+
+// QueryInterceptor wraps the execution of a query as a whole, around all its attempts, while an
+// Interceptor wraps each attempt on a broker. The context a query interceptor passes to next, such as
+// one holding a parent span, is the one the attempts see.
+//
+//	func timeQueries(next pinot.QueryFunc) pinot.QueryFunc {
+//		return func(ctx context.Context, req *pinot.Request) (*pinot.BrokerResponse, error) {
+//			start := time.Now()
+//			resp, err := next(ctx, req)
+//			log.Printf("%s took %s, retries included", req.SQL(), time.Since(start))
+//			return resp, err
+//		}
+//	}
+type QueryInterceptor func(next QueryFunc) QueryFunc
+
+// chainQueryInterceptors wraps query with the query interceptors, the first one being the outermost.
+func chainQueryInterceptors(query QueryFunc, interceptors []QueryInterceptor) QueryFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		query = interceptors[i](query)
+	}
+	return query
+}
+
+// baseTransport exposes a clientTransport as the Transport wrapped by interceptors.
+type baseTransport struct {
+	transport clientTransport
+}
+
+func (t baseTransport) Execute(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+	return t.transport.execute(ctx, brokerAddress, request)
+}
+
+// chainInterceptors wraps the transport with the interceptors, the first one being the outermost.
+// It returns nil when there is no interceptor.
+func chainInterceptors(transport clientTransport, interceptors []Interceptor) Transport {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return wrapTransport(baseTransport{transport: transport}, interceptors)
+}
+
+// wrapTransport wraps next with the interceptors, the first one being the outermost.
+func wrapTransport(next Transport, interceptors []Interceptor) Transport {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next = interceptors[i](next)
+	}
+	return next
 }
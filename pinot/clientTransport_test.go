@@ -0,0 +1,284 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/startreedata/pinot-client-go/pinot/proto"
+)
+
+// recordingInterceptor appends its name to calls before and after executing the query.
+func recordingInterceptor(name string, calls *[]string) Interceptor {
+	return func(next Transport) Transport {
+		return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+			*calls = append(*calls, name+" "+request.SQL())
+			resp, err := next.Execute(ctx, brokerAddress, request)
+			*calls = append(*calls, name+" done")
+			return resp, err
+		})
+	}
+}
+
+func rewritingInterceptor(next Transport) Transport {
+	return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+		request = request.WithSQL(strings.ReplaceAll(request.SQL(), "select *", "select id")).
+			WithQueryOptions(&QueryOptions{MaxExecutionThreads: 2}).
+			WithHeader("Authorization", "Bearer token")
+		return next.Execute(ctx, brokerAddress, request)
+	})
+}
+
+func TestInterceptorsHTTP(t *testing.T) {
+	var body map[string]string
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[],"numDocsScanned":3}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	var calls []string
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:      []string{ts.URL},
+		ExtraHTTPHeader: map[string]string{"Authorization": "Basic static"},
+		QueryOptions:    &QueryOptions{SkipUpsert: true},
+		Interceptors:    []Interceptor{recordingInterceptor("outer", &calls), rewritingInterceptor, recordingInterceptor("inner", &calls)},
+	})
+	require.NoError(t, err)
+	resp, err := conn.ExecuteSQL("t", "select * from t")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.NumDocsScanned)
+
+	assert.Equal(t, []string{"outer select * from t", "inner select id from t", "inner done", "outer done"}, calls)
+	assert.Equal(t, "select id from t", body["sql"])
+	assert.Contains(t, body["queryOptions"], "maxExecutionThreads=2;skipUpsert=true")
+	assert.Equal(t, []string{"Bearer token"}, header.Values("Authorization"))
+
+	// Views share the interceptors of the connection.
+	calls = nil
+	_, err = conn.With(WithTrace()).ExecuteSQL("t", "select 1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer select 1", "inner select 1", "inner done", "outer done"}, calls)
+}
+
+func TestInterceptorsGrpc(t *testing.T) {
+	server, listener, mockServer := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[]}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		jsonRowBlock(t, [][]interface{}{{json.Number("1")}}),
+	})
+	defer server.Stop()
+
+	var seen *BrokerResponse
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{listener.Addr().String()},
+		GrpcConfig: &GrpcConfig{Encoding: "JSON", Compression: "NONE", ExtraMetadata: map[string]string{"Authorization": "Basic static"}},
+		Interceptors: []Interceptor{
+			func(next Transport) Transport {
+				return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+					resp, err := next.Execute(ctx, brokerAddress, request)
+					seen = resp
+					return resp, err
+				})
+			},
+			rewritingInterceptor,
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	resp, err := conn.ExecuteSQL("t", "select * from t")
+	require.NoError(t, err)
+	assert.Same(t, resp, seen)
+	assert.Len(t, resp.ResultTable.Rows, 1)
+
+	require.NotNil(t, mockServer.lastRequest)
+	assert.Equal(t, "select id from t", mockServer.lastRequest.Sql)
+	assert.Equal(t, "Bearer token", mockServer.lastRequest.Metadata["Authorization"])
+	assert.Contains(t, mockServer.lastRequest.Metadata["queryOptions"], "maxExecutionThreads=2")
+	// Request headers are also sent as gRPC call metadata.
+	assert.Equal(t, []string{"Bearer token"}, mockServer.lastMetadata.Get("Authorization"))
+	assert.Empty(t, mockServer.lastMetadata.Get("queryOptions"))
+}
+
+func TestInterceptorsQueryStreamGrpc(t *testing.T) {
+	server, listener, mockServer := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[],"numDocsScanned":2}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		jsonRowBlock(t, [][]interface{}{{json.Number("1")}, {json.Number("2")}}),
+	})
+	defer server.Stop()
+
+	injected := errors.New("injected failure")
+	var calls []string
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{listener.Addr().String()},
+		GrpcConfig: &GrpcConfig{Encoding: "JSON", Compression: "NONE"},
+		QueryInterceptors: []QueryInterceptor{func(next QueryFunc) QueryFunc {
+			return func(ctx context.Context, request *Request) (*BrokerResponse, error) {
+				resp, err := next(ctx, request)
+				calls = append(calls, fmt.Sprintf("query %v", err))
+				return resp, err
+			}
+		}},
+		Interceptors: []Interceptor{func(next Transport) Transport {
+			return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+				if request.SQL() == "fail" {
+					return nil, injected
+				}
+				resp, err := next.Execute(ctx, brokerAddress, request.WithHeader("Authorization", "Bearer token"))
+				require.NoError(t, err)
+				calls = append(calls, fmt.Sprintf("attempt %d docs", resp.NumDocsScanned))
+				return resp, err
+			})
+		}},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := conn.QueryStream(context.Background(), "t", "select id from t")
+	require.NoError(t, err)
+	var ids []interface{}
+	for stream.Next() {
+		ids = append(ids, stream.Row()[0])
+	}
+	require.NoError(t, stream.Err())
+	require.NoError(t, stream.Close())
+	assert.Equal(t, []interface{}{json.Number("1"), json.Number("2")}, ids)
+	assert.Equal(t, []string{"attempt 2 docs", "query <nil>"}, calls)
+	assert.Equal(t, []string{"Bearer token"}, mockServer.lastMetadata.Get("Authorization"))
+	assert.Equal(t, "Bearer token", mockServer.lastRequest.Metadata["Authorization"])
+
+	_, err = conn.QueryStream(context.Background(), "t", "fail")
+	assert.ErrorIs(t, err, injected)
+}
+
+func TestInterceptorsSeeErrorsAndRetries(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	var errs []error
+	var queryIDs []string
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{ts.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 2},
+		Interceptors: []Interceptor{func(next Transport) Transport {
+			return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+				assert.Equal(t, ts.URL, brokerAddress)
+				queryIDs = append(queryIDs, request.ClientQueryID())
+				resp, err := next.Execute(ctx, brokerAddress, request)
+				errs = append(errs, err)
+				return resp, err
+			})
+		}},
+	})
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQL("t", "select 1")
+	require.NoError(t, err)
+
+	require.Len(t, errs, 2)
+	var httpErr *BrokerHTTPError
+	require.ErrorAs(t, errs[0], &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.NoError(t, errs[1])
+	assert.Equal(t, queryIDs[0], queryIDs[1])
+	assert.NotEmpty(t, queryIDs[0])
+}
+
+type attemptKey struct{}
+
+func TestQueryInterceptorsWrapRetries(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	var calls []string
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{ts.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 2},
+		QueryInterceptors: []QueryInterceptor{func(next QueryFunc) QueryFunc {
+			return func(ctx context.Context, request *Request) (*BrokerResponse, error) {
+				calls = append(calls, "query "+request.SQL())
+				resp, err := next(context.WithValue(ctx, attemptKey{}, "parent"), request.WithSQL("select 2"))
+				calls = append(calls, "query done")
+				return resp, err
+			}
+		}},
+		Interceptors: []Interceptor{func(next Transport) Transport {
+			return TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+				calls = append(calls, fmt.Sprintf("attempt %s in %v", request.SQL(), ctx.Value(attemptKey{})))
+				return next.Execute(ctx, brokerAddress, request)
+			})
+		}},
+	})
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQL("t", "select 1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"query select 1", "attempt select 2 in parent", "attempt select 2 in parent", "query done"}, calls)
+
+	// Connection views keep the query interceptors.
+	calls = nil
+	_, err = conn.With(WithTrace()).ExecuteSQL("t", "select 1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"query select 1", "attempt select 2 in parent", "query done"}, calls)
+}
+
+func TestInterceptorFaultInjection(t *testing.T) {
+	injected := errors.New("injected failure")
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{"localhost:8000"},
+		Interceptors: []Interceptor{func(Transport) Transport {
+			return TransportFunc(func(context.Context, string, *Request) (*BrokerResponse, error) {
+				return nil, injected
+			})
+		}},
+	})
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQL("t", "select 1")
+	assert.ErrorIs(t, err, injected)
+}
+
+func TestRequestWithMethodsCopy(t *testing.T) {
+	request := &Request{queryFormat: "sql", query: "select 1", queryOptions: &QueryOptions{SkipUpsert: true}}
+	withHeader := request.WithHeader("a", "1")
+	withHeaders := withHeader.WithHeader("b", "2")
+	rewritten := withHeaders.WithSQL("select 2").WithQueryOptions(&QueryOptions{MaxExecutionThreads: 4})
+
+	assert.Nil(t, request.Headers())
+	assert.Equal(t, map[string]string{"a": "1"}, withHeader.Headers())
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, rewritten.Headers())
+	assert.Equal(t, "select 1", withHeaders.SQL())
+	assert.Equal(t, "select 2", rewritten.SQL())
+	assert.Equal(t, &QueryOptions{SkipUpsert: true}, request.QueryOptions())
+	assert.Equal(t, &QueryOptions{SkipUpsert: true, MaxExecutionThreads: 4}, rewritten.QueryOptions())
+}
@@ -1,6 +1,10 @@
 package pinot
 
-import "time"
+import (
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
 
 // ClientConfig configs to create a PinotDbConnection
 type ClientConfig struct {
@@ -18,6 +22,32 @@ type ClientConfig struct {
 	HTTPTimeout time.Duration
 	// UseMultistageEngine is a flag to enable multistage query execution engine
 	UseMultistageEngine bool
+	// QueryOptions are the default query options sent with every query of the connection
+	QueryOptions *QueryOptions
+	// ExceptionsAsErrors makes queries return a non-empty BrokerResponse.Exceptions as an error,
+	// matching *QueryException with errors.As. The response is returned along with the error.
+	ExceptionsAsErrors bool
+	// RetryPolicy retries queries failing on a broker, possibly on another broker serving the table.
+	// Queries are not retried when it is nil.
+	RetryPolicy *RetryPolicy
+	// LoadBalancer picks the broker of each query among the brokers serving the table,
+	// e.g. NewRoundRobinLoadBalancer(). Brokers are picked at random when it is nil.
+	LoadBalancer LoadBalancer
+	// CircuitBreaker enables the health tracking of brokers, temporarily ejecting the failing ones
+	CircuitBreaker *CircuitBreakerConfig
+	// Interceptors wrap the transport executing queries, HTTP or gRPC, the first one being the outermost.
+	// They apply to every attempt of a query, including the opening of the streams of QueryStream
+	// and QueryArrow.
+	Interceptors []Interceptor
+	// QueryInterceptors wrap the execution of each query as a whole, retries included, the first one
+	// being the outermost. They run before Interceptors.
+	QueryInterceptors []QueryInterceptor
+	// BrokerSelectorObserver is notified of the broker lists discovered by the connection and of
+	// their refresh failures
+	BrokerSelectorObserver BrokerSelectorObserver
+	// Logger receives the log messages of the connection, including the conversion errors of the
+	// accessors of its result tables. Defaults to the standard logrus logger.
+	Logger Logger
 }
 
 // GrpcConfig describes how to configure broker gRPC queries
@@ -34,6 +64,20 @@ type GrpcConfig struct {
 	ExtraMetadata map[string]string
 	// TLS config for secure gRPC connections.
 	TLSConfig *GrpcTLSConfig
+	// PoolSize is the number of connections kept open to each broker - defaults to 1.
+	// Queries are spread over the connections of a broker in turn.
+	PoolSize int
+	// IdleTimeout closes the connections to a broker that received no query for this long - defaults to 10m.
+	IdleTimeout time.Duration
+	// KeepAliveTime enables keepalive pings on connections without activity for this long.
+	// It must not be lower than the minimum ping interval accepted by the brokers. 0 disables keepalive.
+	KeepAliveTime time.Duration
+	// KeepAliveTimeout is how long to wait for a keepalive ping acknowledgement before closing the connection - defaults to 20s.
+	KeepAliveTimeout time.Duration
+	// KeepAlivePermitWithoutStream sends keepalive pings even when no query is in flight.
+	KeepAlivePermitWithoutStream bool
+	// ArrowAllocator allocates the memory of the records returned by Connection.QueryArrow - defaults to the Go allocator.
+	ArrowAllocator memory.Allocator
 }
 
 // GrpcTLSConfig configures TLS for gRPC connections.
@@ -1,50 +1,510 @@
 package pinot
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"math/rand"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Connection to Pinot, normally created through calls to the {@link ConnectionFactory}.
 type Connection struct {
-	transport           clientTransport
-	brokerSelector      brokerSelector
+	transport clientTransport
+	// intercepted is the transport wrapped by ClientConfig.Interceptors, nil when there are none
+	intercepted Transport
+	// interceptors wrap the opening of streams, see ClientConfig.Interceptors
+	interceptors []Interceptor
+	// queryInterceptors wrap the execution of queries as a whole, see ClientConfig.QueryInterceptors
+	queryInterceptors []QueryInterceptor
+	brokerSelector    brokerSelector
+	// loadBalancer picks the broker of each query among the available ones, nil for a random pick
+	loadBalancer LoadBalancer
+	// health tracks the circuit breakers of brokers, nil when circuit breaking is disabled
+	health *brokerHealthTracker
+	logger Logger
+	requestSettings
+	// parent is the connection a view created by With derives from, nil for the connection itself
+	parent *Connection
+	closed atomic.Bool
+	// running maps the client query IDs of the queries in flight to their *runningQuery, see Cancel
+	running sync.Map
+}
+
+// requestSettings are the per-request settings carried by a Connection and its views.
+type requestSettings struct {
 	trace               bool
 	useMultistageEngine bool
+	queryOptions        *QueryOptions
+	exceptionsAsErrors  bool
+	retryPolicy         *RetryPolicy
+}
+
+// RequestOption configures the request settings of a view created by Connection.With.
+type RequestOption func(*requestSettings)
+
+// WithTrace enables query tracing for requests issued through the view.
+func WithTrace() RequestOption {
+	return func(s *requestSettings) {
+		s.trace = true
+	}
+}
+
+// WithMultistage enables the multistage query engine for requests issued through the view.
+func WithMultistage() RequestOption {
+	return func(s *requestSettings) {
+		s.useMultistageEngine = true
+	}
+}
+
+// WithQueryOptions merges options into the query options of the view, non-zero values taking precedence.
+func WithQueryOptions(options *QueryOptions) RequestOption {
+	return func(s *requestSettings) {
+		s.queryOptions = s.queryOptions.merge(options)
+	}
+}
+
+// WithNullHandling enables Pinot null handling for requests issued through the view: null values are
+// returned as nulls instead of column default values, see ResultTable.IsNull.
+// Use ClientConfig.QueryOptions to enable it for every request of a connection.
+func WithNullHandling() RequestOption {
+	return WithQueryOptions(&QueryOptions{EnableNullHandling: true})
+}
+
+// WithExceptionsAsErrors makes queries issued through the view return the exceptions of the broker
+// response as an error, see ClientConfig.ExceptionsAsErrors.
+func WithExceptionsAsErrors() RequestOption {
+	return func(s *requestSettings) {
+		s.exceptionsAsErrors = true
+	}
+}
+
+// WithRetryPolicy replaces the retry policy of the view, see ClientConfig.RetryPolicy.
+// A nil policy disables retries.
+func WithRetryPolicy(policy *RetryPolicy) RequestOption {
+	return func(s *requestSettings) {
+		s.retryPolicy = policy
+	}
+}
+
+// With returns a lightweight view of the connection with its own request settings.
+// The view shares the transport, the broker selector and the closed state of c, while settings
+// applied to it do not affect c or other views. Views do not own these shared resources: closing
+// a view is a no-op, they are released by closing the connection the views derive from.
+// It is safe to derive views concurrently,
+// e.g. to enable tracing for a single debug query:
+//
+//	resp, err := conn.With(pinot.WithTrace(), pinot.WithMultistage()).ExecuteSQL(table, query)
+func (c *Connection) With(opts ...RequestOption) *Connection {
+	view := &Connection{
+		transport:         c.transport,
+		intercepted:       c.intercepted,
+		interceptors:      c.interceptors,
+		queryInterceptors: c.queryInterceptors,
+		brokerSelector:    c.brokerSelector,
+		loadBalancer:      c.loadBalancer,
+		health:            c.health,
+		logger:            c.logger,
+		requestSettings:   c.requestSettings,
+		parent:            c.root(),
+	}
+	for _, opt := range opts {
+		opt(&view.requestSettings)
+	}
+	return view
+}
+
+// root returns the connection owning the shared resources of c.
+func (c *Connection) root() *Connection {
+	if c.parent != nil {
+		return c.parent
+	}
+	return c
+}
+
+// Close stops the background broker discovery of the connection (Zookeeper watcher or controller polling)
+// and broker health probing, closes the Zookeeper session and releases transport resources.
+// Queries issued after Close fail with ErrConnectionClosed, including those issued through its views.
+// Calling Close more than once is a no-op, as is closing a view created by With.
+func (c *Connection) Close() error {
+	if c.parent != nil {
+		return nil
+	}
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	var errs []error
+	if c.brokerSelector != nil {
+		if err := c.brokerSelector.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close broker selector: %w", err))
+		}
+	}
+	if c.health != nil {
+		c.health.close()
+	}
+	if c.transport != nil {
+		if err := c.transport.close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close transport: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // UseMultistageEngine for the connection
+//
+// It mutates the connection and must not be called while queries are running on it.
+// Use conn.With(WithMultistage()) to enable the multistage engine for some queries only.
 func (c *Connection) UseMultistageEngine(useMultistageEngine bool) {
 	c.useMultistageEngine = useMultistageEngine
 }
 
 // ExecuteSQL for a given table
 func (c *Connection) ExecuteSQL(table string, query string) (*BrokerResponse, error) {
-	brokerAddress, err := c.brokerSelector.selectBroker(table)
+	return c.ExecuteSQLContext(context.Background(), table, query)
+}
+
+// ExecuteSQLContext executes an SQL query for a given table.
+// Cancelling ctx or reaching its deadline aborts the in-flight broker request,
+// and the remaining time until the deadline is sent to the broker as the timeoutMs query option.
+func (c *Connection) ExecuteSQLContext(ctx context.Context, table string, query string) (*BrokerResponse, error) {
+	return c.executeSQL(ctx, table, query, nil)
+}
+
+// ExecuteSQLWithOptions executes an SQL query for a given table with per-query options.
+// The options are merged with the connection level defaults, and non-zero per-query values take precedence.
+func (c *Connection) ExecuteSQLWithOptions(ctx context.Context, table string, query string, options *QueryOptions) (*BrokerResponse, error) {
+	return c.executeSQL(ctx, table, query, options)
+}
+
+func (c *Connection) executeSQL(ctx context.Context, table string, query string, options *QueryOptions) (*BrokerResponse, error) {
+	if c.root().closed.Load() {
+		return nil, ErrConnectionClosed
+	}
+	request := c.newRequest(table, query, options)
+	brokerResp, err := chainQueryInterceptors(c.executeWithRetries, c.queryInterceptors)(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if c.exceptionsAsErrors {
+		// The response is returned along with the error, so partial results remain available.
+		return brokerResp, brokerResp.Err()
+	}
+	return brokerResp, nil
+}
+
+// executeWithRetries executes the request on the brokers picked for its table, the QueryFunc wrapped by
+// the query interceptors.
+func (c *Connection) executeWithRetries(ctx context.Context, request *Request) (*BrokerResponse, error) {
+	var brokerResp *BrokerResponse
+	err := c.withRetries(ctx, request.table, request.query, func(brokerAddress string) error {
+		var err error
+		brokerResp, err = c.executeOnBroker(ctx, brokerAddress, request)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return brokerResp, nil
+}
+
+// QueryStream executes an SQL query for a given table and returns a stream over the rows of its result.
+// With the gRPC transport, rows are decoded one response block at a time as they are consumed, so large
+// results do not have to fit in memory; with the HTTP transport, the response is received fully first.
+// Cancelling ctx or closing the stream tears down the RPC. Failures to start the query are retried
+// according to the retry policy, while failures in the middle of the stream are returned by its Err method.
+// The interceptors of the connection wrap the opening of the stream, see Interceptor.
+func (c *Connection) QueryStream(ctx context.Context, table string, query string) (*RowStream, error) {
+	if c.root().closed.Load() {
+		return nil, ErrConnectionClosed
+	}
+	request := c.newRequest(table, query, nil)
+	streaming, ok := c.transport.(streamingTransport)
+	stream, err := queryStream(c, ctx, request, func(ctx context.Context, brokerAddress string, request *Request) (*RowStream, error) {
+		if !ok {
+			brokerResp, err := c.executeOnBroker(ctx, brokerAddress, request)
+			if err != nil {
+				return nil, err
+			}
+			return newBufferedRowStream(brokerResp), nil
+		}
+		return openStream(c, ctx, brokerAddress, request, streaming.stream)
+	})
+	if err != nil {
+		return nil, err
+	}
+	stream.clientQueryID = request.clientQueryID
+	if c.exceptionsAsErrors {
+		if err := stream.Metadata().Err(); err != nil {
+			return nil, errors.Join(err, stream.Close())
+		}
+	}
+	return stream, nil
+}
+
+// QueryArrow executes an SQL query for a given table and returns a reader over the Arrow record batches
+// of its result, as sent by the broker. It requires the gRPC transport with the ARROW encoding, and returns
+// ErrArrowUnsupported otherwise. Record batches are received as they are consumed; releasing the reader or
+// cancelling ctx tears down the RPC. Failures to start the query are retried according to the retry policy.
+// The interceptors of the connection wrap the opening of the stream, see Interceptor.
+func (c *Connection) QueryArrow(ctx context.Context, table string, query string) (*ArrowRecordReader, error) {
+	if c.root().closed.Load() {
+		return nil, ErrConnectionClosed
+	}
+	transport, ok := c.transport.(arrowTransport)
+	if !ok || !transport.arrowEnabled() {
+		return nil, ErrArrowUnsupported
+	}
+	request := c.newRequest(table, query, nil)
+	reader, err := queryStream(c, ctx, request, func(ctx context.Context, brokerAddress string, request *Request) (*ArrowRecordReader, error) {
+		return openStream(c, ctx, brokerAddress, request, transport.arrowRecords)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reader.clientQueryID = request.clientQueryID
+	if c.exceptionsAsErrors {
+		if err := reader.Metadata().Err(); err != nil {
+			reader.Release()
+			return nil, err
+		}
+	}
+	return reader, nil
+}
+
+// resultStream is the stream of the results of a query, a RowStream or an ArrowRecordReader.
+type resultStream interface {
+	// Metadata returns the broker response received before the results
+	Metadata() *BrokerResponse
+	// setOnDone sets the function called with the outcome of the stream once it is done
+	setOnDone(onDone func(err error))
+	// close releases the stream before it was returned to the caller
+	close() error
+}
+
+// queryStream opens the stream of a query through the query interceptors, on the brokers picked for its
+// table until an attempt succeeds. The query interceptors see the metadata of the stream as the response.
+func queryStream[S resultStream](c *Connection, ctx context.Context, request *Request,
+	openOnBroker func(ctx context.Context, brokerAddress string, request *Request) (S, error)) (S, error) {
+	var stream S
+	opened := false
+	_, err := chainQueryInterceptors(func(ctx context.Context, request *Request) (*BrokerResponse, error) {
+		err := c.withRetries(ctx, request.table, request.query, func(brokerAddress string) error {
+			var err error
+			stream, err = openOnBroker(ctx, brokerAddress, request)
+			opened = err == nil
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return stream.Metadata(), nil
+	}, c.queryInterceptors)(ctx, request)
+	if err == nil && !opened {
+		err = fmt.Errorf("query interceptors returned without opening the stream of query %s", request.query)
+	}
+	if err != nil {
+		if opened {
+			// A query interceptor failed the query once its stream was open.
+			err = errors.Join(err, stream.close())
+		}
+		var none S
+		return none, err
+	}
+	return stream, nil
+}
+
+// openStream opens the stream of a query on the broker through the interceptors, which see the metadata of
+// the stream as the response. Like executeOnBroker, it reports the outcome of the stream to the load
+// balancer and the health tracker once it is done, and the stream can be cancelled while it runs.
+func openStream[S resultStream](c *Connection, ctx context.Context, brokerAddress string, request *Request,
+	open func(ctx context.Context, brokerAddress string, request *Request) (S, error)) (S, error) {
+	done := c.trackBroker(brokerAddress)
+	streamCtx, stopRunning := c.startRunning(ctx, brokerAddress, request)
+	var stream S
+	opened := false
+	_, err := wrapTransport(TransportFunc(func(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+		var err error
+		stream, err = open(ctx, brokerAddress, request)
+		if err != nil {
+			return nil, err
+		}
+		opened = true
+		return stream.Metadata(), nil
+	}), c.interceptors).Execute(streamCtx, brokerAddress, request)
+	if err == nil && !opened {
+		err = fmt.Errorf("interceptors returned without opening the stream of query %s", request.query)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("unable to find an available broker for table %s, Error: %v", table, err)
+		if opened {
+			// An interceptor failed the attempt once the stream was open.
+			err = errors.Join(err, stream.close())
+		}
+		stopRunning()
+		done(ctx, err)
+		var none S
+		return none, err
+	}
+	stream.setOnDone(func(err error) {
+		stopRunning()
+		done(ctx, err)
+	})
+	return stream, nil
+}
+
+// newRequest returns the request of a query, carrying the request settings of the connection.
+// Each request has a client query ID, so that it can be cancelled.
+func (c *Connection) newRequest(table string, query string, options *QueryOptions) *Request {
+	queryOptions := c.queryOptions.merge(options)
+	clientQueryID := uuid.New().String()
+	if queryOptions != nil {
+		if id, ok := queryOptions.Extra[clientQueryIDOption]; ok {
+			clientQueryID = id
+		} else if queryOptions.ClientQueryID != "" {
+			clientQueryID = queryOptions.ClientQueryID
+		}
 	}
-	brokerResp, err := c.transport.execute(brokerAddress, &Request{
+	return &Request{
 		queryFormat:         "sql",
+		table:               table,
 		query:               query,
 		trace:               c.trace,
 		useMultistageEngine: c.useMultistageEngine,
-	})
+		queryOptions:        queryOptions,
+		clientQueryID:       clientQueryID,
+	}
+}
+
+// withRetries runs attempt on the brokers picked for the table until it succeeds,
+// or fails with an error the retry policy does not retry.
+func (c *Connection) withRetries(ctx context.Context, table string, query string, attempt func(brokerAddress string) error) error {
+	var failedBrokers map[string]bool
+	for n := 1; ; n++ {
+		brokerAddress, err := c.pickBroker(table, failedBrokers)
+		if err != nil {
+			return fmt.Errorf("unable to find an available broker for table %s, Error: %w", table, err)
+		}
+		err = attempt(brokerAddress)
+		if err == nil {
+			return nil
+		}
+		if !c.retryPolicy.shouldRetry(ctx, n, err) {
+			return fmt.Errorf("caught exception to execute SQL query %s, Error: %w", query, markTimeout(err))
+		}
+		loggerOrDefault(c.logger).Warn(ctx, "Query attempt failed, retrying", "attempt", n, "broker", brokerAddress, "error", err)
+		if failedBrokers == nil {
+			failedBrokers = make(map[string]bool)
+		}
+		failedBrokers[brokerAddress] = true
+		if waitErr := c.retryPolicy.wait(ctx, n); waitErr != nil {
+			return fmt.Errorf("caught exception to execute SQL query %s, Error: %w", query, markTimeout(err))
+		}
+	}
+}
+
+// pickBroker selects the broker for the next attempt of a query, through the load balancer when one is set.
+// Brokers ejected by their circuit breaker are skipped.
+// When the retry policy avoids failed brokers, the brokers that already failed for the query are skipped
+// while others remain.
+func (c *Connection) pickBroker(table string, failedBrokers map[string]bool) (string, error) {
+	avoidFailed := len(failedBrokers) > 0 && c.retryPolicy != nil && c.retryPolicy.AvoidFailedBrokers
+	if c.loadBalancer == nil && c.health == nil && !avoidFailed {
+		return c.brokerSelector.selectBroker(table)
+	}
+	brokers, err := c.brokerSelector.availableBrokers(table)
 	if err != nil {
-		return nil, fmt.Errorf("caught exception to execute SQL query %s, Error: %w", query, err)
+		return "", err
+	}
+	if avoidFailed {
+		brokers = excludeBrokers(brokers, failedBrokers)
+	}
+	if c.health != nil {
+		brokers = c.health.filter(brokers)
+	}
+	if c.loadBalancer != nil {
+		return c.loadBalancer.Pick(brokers), nil
+	}
+	// #nosec G404
+	return brokers[rand.Intn(len(brokers))], nil
+}
+
+// executeOnBroker sends the request to the broker through the interceptors, reporting its outcome
+// to the load balancer and the health tracker when they are set.
+// The query is cancelled on the broker when ctx is cancelled after the request was sent, before the
+// response is received.
+func (c *Connection) executeOnBroker(ctx context.Context, brokerAddress string, request *Request) (*BrokerResponse, error) {
+	done := c.trackBroker(brokerAddress)
+	running := &c.root().running
+	query := &runningQuery{brokerAddress: brokerAddress}
+	running.Store(request.clientQueryID, query)
+	ctx, sent := withRequestSent(ctx)
+	var brokerResp *BrokerResponse
+	var err error
+	if c.intercepted != nil {
+		brokerResp, err = c.intercepted.Execute(ctx, brokerAddress, request)
+	} else {
+		brokerResp, err = c.transport.execute(ctx, brokerAddress, request)
+	}
+	running.CompareAndDelete(request.clientQueryID, query)
+	if brokerResp != nil && brokerResp.ResultTable != nil {
+		brokerResp.ResultTable.logger = c.logger
+	}
+	done(ctx, err)
+	if err != nil && sent.Load() && errors.Is(ctx.Err(), context.Canceled) {
+		c.cancelAbandoned(brokerAddress, request.clientQueryID)
 	}
 	return brokerResp, err
 }
 
+// trackBroker reports a query sent to the broker to the load balancer and the health tracker
+// when they are set. The returned function reports the outcome of the query.
+func (c *Connection) trackBroker(brokerAddress string) func(ctx context.Context, err error) {
+	if c.loadBalancer == nil && c.health == nil {
+		return func(context.Context, error) {}
+	}
+	if c.loadBalancer != nil {
+		c.loadBalancer.Begin(brokerAddress)
+	}
+	if c.health != nil {
+		c.health.begin(brokerAddress)
+	}
+	start := time.Now()
+	return func(ctx context.Context, err error) {
+		if c.loadBalancer != nil {
+			c.loadBalancer.End(brokerAddress, time.Since(start), err)
+		}
+		if c.health != nil {
+			c.health.end(ctx, brokerAddress, err)
+		}
+	}
+}
+
+// BrokerHealth returns the health of the brokers queried or probed so far, sorted by address.
+// It returns nil when ClientConfig.CircuitBreaker is not set.
+func (c *Connection) BrokerHealth() []BrokerHealth {
+	if c.health == nil {
+		return nil
+	}
+	return c.health.snapshot()
+}
+
 // ExecuteSQLWithParams executes an SQL query with parameters for a given table
 func (c *Connection) ExecuteSQLWithParams(table string, queryPattern string, params []interface{}) (*BrokerResponse, error) {
+	return c.ExecuteSQLWithParamsContext(context.Background(), table, queryPattern, params)
+}
+
+// ExecuteSQLWithParamsContext executes an SQL query with parameters for a given table, honoring ctx like ExecuteSQLContext
+func (c *Connection) ExecuteSQLWithParamsContext(ctx context.Context, table string, queryPattern string, params []interface{}) (*BrokerResponse, error) {
 	query, err := formatQuery(queryPattern, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format query: %v", err)
 	}
-	return c.ExecuteSQL(table, query)
+	return c.ExecuteSQLContext(ctx, table, query)
 }
 
 func formatQuery(queryPattern string, params []interface{}) (string, error) {
@@ -100,11 +560,16 @@ func escapeStringValue(s string) string {
 }
 
 // OpenTrace for the connection
+//
+// It mutates the connection and must not be called while queries are running on it.
+// Use conn.With(WithTrace()) to enable tracing for some queries only.
 func (c *Connection) OpenTrace() {
 	c.trace = true
 }
 
 // CloseTrace for the connection
+//
+// It mutates the connection and must not be called while queries are running on it.
 func (c *Connection) CloseTrace() {
 	c.trace = false
 }
@@ -1,6 +1,7 @@
 package pinot
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -73,57 +74,88 @@ func NewWithConfigAndClient(config *ClientConfig, httpClient *http.Client) (*Con
 		clientCopy.Timeout = config.HTTPTimeout
 		client = &clientCopy
 	}
+	logger := loggerOrDefault(config.Logger)
 	var transport clientTransport
 	if config.GrpcConfig != nil {
 		grpcTransport, err := grpcTransportFactory(config.GrpcConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize grpc transport: %v", err)
 		}
+		grpcTransport.pool.logger = logger
 		transport = grpcTransport
 	} else {
 		transport = &jsonAsyncHTTPClientTransport{
 			client: client,
 			header: config.ExtraHTTPHeader,
+			logger: logger,
 		}
 	}
 
-	var conn *Connection
+	var selector brokerSelector
 	if config.ZkConfig != nil {
-		conn = &Connection{
-			transport: transport,
-			brokerSelector: &dynamicBrokerSelector{
-				zkConfig: config.ZkConfig,
-			},
-			useMultistageEngine: config.UseMultistageEngine,
+		selector = &dynamicBrokerSelector{
+			zkConfig:                 config.ZkConfig,
+			tableAwareBrokerSelector: tableAwareBrokerSelector{observer: config.BrokerSelectorObserver, logger: logger},
 		}
 	}
 	if len(config.BrokerList) > 0 {
-		conn = &Connection{
-			transport: transport,
-			brokerSelector: &simpleBrokerSelector{
-				brokerList: config.BrokerList,
-			},
-			useMultistageEngine: config.UseMultistageEngine,
+		selector = &simpleBrokerSelector{
+			brokerList: config.BrokerList,
+			observer:   config.BrokerSelectorObserver,
 		}
 	}
 	if config.ControllerConfig != nil {
-		conn = &Connection{
-			transport: transport,
-			brokerSelector: &controllerBasedSelector{
-				config: config.ControllerConfig,
-				client: client,
-			},
-			useMultistageEngine: config.UseMultistageEngine,
+		selector = &controllerBasedSelector{
+			config:                   config.ControllerConfig,
+			client:                   client,
+			tableAwareBrokerSelector: tableAwareBrokerSelector{observer: config.BrokerSelectorObserver, logger: logger},
 		}
 	}
-	if conn != nil {
+	if selector != nil {
+		conn := &Connection{
+			transport:         transport,
+			intercepted:       chainInterceptors(transport, config.Interceptors),
+			interceptors:      config.Interceptors,
+			queryInterceptors: config.QueryInterceptors,
+			brokerSelector:    selector,
+			loadBalancer:      config.LoadBalancer,
+			logger:            logger,
+			requestSettings: requestSettings{
+				useMultistageEngine: config.UseMultistageEngine,
+				queryOptions:        config.QueryOptions,
+				exceptionsAsErrors:  config.ExceptionsAsErrors,
+				retryPolicy:         config.RetryPolicy,
+			},
+		}
+		if config.CircuitBreaker != nil {
+			conn.health = newBrokerHealthTracker(config.CircuitBreaker)
+			conn.health.logger = logger
+		}
 		// TODO: error handling results into `make test` failure.
 		if err := conn.brokerSelector.init(); err != nil {
-			return conn, fmt.Errorf("failed to initialize broker selector: %v", err)
+			// The connection is unusable: release the transport and anything the selector started.
+			initErr := fmt.Errorf("failed to initialize broker selector: %v", err)
+			if closeErr := conn.Close(); closeErr != nil {
+				return conn, errors.Join(initErr, closeErr)
+			}
+			return conn, initErr
+		}
+		if grpcTransport, ok := transport.(*grpcBrokerClientTransport); ok {
+			// Pooled connections to brokers that left the broker list are closed.
+			grpcTransport.pool.knownBrokers = func() ([]string, error) {
+				return selector.availableBrokers("")
+			}
+		}
+		if conn.health != nil && config.GrpcConfig == nil {
+			conn.health.startProbing(selector, client, config.ExtraHTTPHeader)
 		}
 		return conn, nil
 	}
-	return nil, fmt.Errorf(
+	configErr := fmt.Errorf(
 		"please specify at least one of Pinot Zookeeper, Pinot Broker or Pinot Controller to connect",
 	)
+	if err := transport.close(); err != nil {
+		return nil, errors.Join(configErr, fmt.Errorf("failed to close transport: %w", err))
+	}
+	return nil, configErr
 }
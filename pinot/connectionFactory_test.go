@@ -118,6 +118,32 @@ func TestNewWithConfigAndClientGrpcTransportError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestNewWithConfigAndClientClosesGrpcTransportOnError(t *testing.T) {
+	original := grpcTransportFactory
+	var created []*grpcBrokerClientTransport
+	grpcTransportFactory = func(config *GrpcConfig) (*grpcBrokerClientTransport, error) {
+		transport, err := original(config)
+		created = append(created, transport)
+		return transport, err
+	}
+	t.Cleanup(func() { grpcTransportFactory = original })
+
+	_, err := NewWithConfigAndClient(&ClientConfig{GrpcConfig: &GrpcConfig{}}, nil)
+	assert.ErrorContains(t, err, "please specify at least one of")
+
+	conn, err := NewWithConfigAndClient(&ClientConfig{
+		ControllerConfig: &ControllerConfig{ControllerAddress: "unsupported://host:9000"},
+		GrpcConfig:       &GrpcConfig{},
+	}, nil)
+	assert.ErrorContains(t, err, "failed to initialize broker selector")
+	assert.True(t, conn.closed.Load())
+
+	require.Len(t, created, 2)
+	for _, transport := range created {
+		assert.True(t, transport.pool.closed)
+	}
+}
+
 func TestPinotClients(t *testing.T) {
 	pinotClient1, err := NewFromZookeeper([]string{"localhost:12181"}, "", "QuickStartCluster")
 	assert.NotNil(t, pinotClient1)
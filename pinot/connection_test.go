@@ -1,12 +1,15 @@
 package pinot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -276,7 +279,8 @@ type mockBrokerSelector struct {
 	mock.Mock
 }
 
-func (m *mockBrokerSelector) init() error { return nil }
+func (m *mockBrokerSelector) init() error  { return nil }
+func (m *mockBrokerSelector) Close() error { return nil }
 func (m *mockBrokerSelector) selectBroker(table string) (string, error) {
 	args := m.Called(table)
 	if val, ok := args.Get(0).(string); ok {
@@ -285,11 +289,21 @@ func (m *mockBrokerSelector) selectBroker(table string) (string, error) {
 	return "", args.Error(1)
 }
 
+func (m *mockBrokerSelector) availableBrokers(table string) ([]string, error) {
+	args := m.Called(table)
+	if val, ok := args.Get(0).([]string); ok {
+		return val, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 type mockTransport struct {
 	mock.Mock
 }
 
-func (m *mockTransport) execute(brokerAddress string, query *Request) (*BrokerResponse, error) {
+func (m *mockTransport) close() error { return nil }
+
+func (m *mockTransport) execute(_ context.Context, brokerAddress string, query *Request) (*BrokerResponse, error) {
 	args := m.Called(brokerAddress, query)
 	if val, ok := args.Get(0).(*BrokerResponse); ok {
 		return val, args.Error(1)
@@ -358,3 +372,179 @@ func TestExecuteSQLWithParams(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.EqualError(t, err, "failed to format query: failed to format parameter: unsupported type: struct {}")
 }
+
+func TestExecuteSQLContextCancelsRequest(t *testing.T) {
+	requestStarted := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Contains(t, request["queryOptions"], "timeoutMs=")
+		close(requestStarted)
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	pinotClient, err := NewFromBrokerList([]string{ts.URL})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-requestStarted
+		cancel()
+	}()
+	// A deadline is required for the timeoutMs assertion in the handler.
+	ctx, cancelTimeout := context.WithTimeout(ctx, time.Minute)
+	defer cancelTimeout()
+	resp, err := pinotClient.ExecuteSQLContext(ctx, "", "select * from baseballStats limit 10")
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExecuteSQLContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	pinotClient, err := NewFromBrokerList([]string{ts.URL})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pinotClient.ExecuteSQLWithParamsContext(ctx, "", "select * from baseballStats where teamID = ?", []interface{}{"OAK"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), queryTimeout(context.Background(), 0))
+	assert.Equal(t, 5*time.Second, queryTimeout(context.Background(), 5*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	assert.Equal(t, 5*time.Second, queryTimeout(ctx, 5*time.Second))
+	timeout := queryTimeout(ctx, time.Hour)
+	assert.True(t, timeout > 50*time.Second && timeout <= time.Minute)
+	timeout = queryTimeout(ctx, 0)
+	assert.True(t, timeout > 50*time.Second && timeout <= time.Minute)
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	assert.Equal(t, time.Millisecond, queryTimeout(expired, 0))
+}
+
+func TestConnectionClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintln(w, `{"resultTable":{"dataSchema":{"columnDataTypes":["LONG"],"columnNames":["cnt"]},"rows":[[1]]},"exceptions":[]}`)
+		assert.Nil(t, err)
+	}))
+	defer ts.Close()
+	pinotClient, err := NewFromBrokerList([]string{ts.URL})
+	assert.Nil(t, err)
+	_, err = pinotClient.ExecuteSQL("", "select count(*) from baseballStats")
+	assert.Nil(t, err)
+
+	assert.Nil(t, pinotClient.Close())
+	_, err = pinotClient.ExecuteSQL("", "select count(*) from baseballStats")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+	stmt, err := pinotClient.Prepare("baseballStats", "select count(*) from baseballStats where teamID = ?")
+	assert.Nil(t, err)
+	_, err = stmt.ExecuteWithParams("OAK")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+	assert.Nil(t, pinotClient.Close())
+}
+
+func TestConnectionCloseWithControllerBasedBrokerSelector(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintln(w, `{"baseballStats":[{"port":8000,"host":"host1","instanceName":"Broker_host1_8000"}]}`)
+		assert.Nil(t, err)
+	}))
+	defer ts.Close()
+	pinotClient, err := NewWithConfig(&ClientConfig{
+		ControllerConfig: &ControllerConfig{
+			ControllerAddress: ts.URL,
+			UpdateFreqMs:      10,
+		},
+	})
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, pinotClient.Close())
+	time.Sleep(20 * time.Millisecond)
+	afterClose := requests.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, afterClose, requests.Load())
+}
+
+func TestConnectionWith(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		// The handler echoes the request settings back through the trace info.
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"exceptions":[],"traceInfo":{"trace":%q,"queryOptions":%q}}`, request["trace"], request["queryOptions"])
+		assert.Nil(t, err)
+	}))
+	defer ts.Close()
+	pinotClient, err := NewWithConfig(&ClientConfig{
+		BrokerList:   []string{ts.URL},
+		QueryOptions: &QueryOptions{MaxExecutionThreads: 2},
+	})
+	assert.Nil(t, err)
+
+	view := pinotClient.With(WithTrace(), WithMultistage(), WithQueryOptions(&QueryOptions{SkipUpsert: true}))
+	assert.True(t, view.trace)
+	assert.True(t, view.useMultistageEngine)
+	assert.False(t, pinotClient.trace)
+	assert.False(t, pinotClient.useMultistageEngine)
+	assert.Equal(t, &QueryOptions{MaxExecutionThreads: 2}, pinotClient.queryOptions)
+	assert.Equal(t, &QueryOptions{MaxExecutionThreads: 2, EnableNullHandling: true}, pinotClient.With(WithNullHandling()).queryOptions)
+	assert.Same(t, pinotClient.transport, view.transport)
+	assert.Same(t, pinotClient.brokerSelector, view.brokerSelector)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(traced bool) {
+			defer wg.Done()
+			conn := pinotClient
+			if traced {
+				conn = pinotClient.With(WithTrace())
+			}
+			resp, queryErr := conn.ExecuteSQL("", "select 1")
+			assert.Nil(t, queryErr)
+			if traced {
+				assert.Equal(t, "true", resp.TraceInfo["trace"])
+			} else {
+				assert.Equal(t, "", resp.TraceInfo["trace"])
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+
+	resp, err := view.ExecuteSQL("", "select 1")
+	assert.Nil(t, err)
+	assert.Equal(t, "true", resp.TraceInfo["trace"])
+	assert.Regexp(t, "^groupByMode=sql;responseFormat=sql;useMultistageEngine=true;maxExecutionThreads=2;skipUpsert=true;clientQueryId=[0-9a-f-]{36}$", resp.TraceInfo["queryOptions"])
+
+	// Views of views derive from the same connection.
+	nested := view.With()
+	assert.Same(t, pinotClient, nested.root())
+	assert.True(t, nested.trace)
+
+	// Only the connection owns its lifecycle: closing a view is a no-op.
+	assert.Nil(t, nested.Close())
+	_, err = view.ExecuteSQL("", "select 1")
+	assert.Nil(t, err)
+
+	assert.Nil(t, pinotClient.Close())
+	_, err = pinotClient.ExecuteSQL("", "select 1")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+	_, err = view.ExecuteSQL("", "select 1")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+}
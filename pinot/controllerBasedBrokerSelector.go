@@ -1,13 +1,12 @@
 package pinot
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -44,6 +43,7 @@ func (s *controllerBasedSelector) init() error {
 	}
 
 	if err = s.updateBrokerData(); err != nil {
+		s.refreshFailed(err)
 		return fmt.Errorf("an error occurred when fetching broker data from controller API: %v", err)
 	}
 	go s.setupInterval()
@@ -51,17 +51,24 @@ func (s *controllerBasedSelector) init() error {
 }
 
 func (s *controllerBasedSelector) setupInterval() {
+	done := s.doneChan()
 	lastInvocation := time.Now()
 	for {
 		nextInvocation := lastInvocation.Add(
 			time.Duration(s.config.UpdateFreqMs) * time.Millisecond,
 		)
-		untilNextInvocation := time.Until(nextInvocation)
-		time.Sleep(untilNextInvocation)
+		timer := time.NewTimer(time.Until(nextInvocation))
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
 
 		err := s.updateBrokerData()
 		if err != nil {
-			log.Errorf("caught exception when updating broker data, Error: %v", err)
+			s.refreshFailed(err)
+			loggerOrDefault(s.logger).Error(context.Background(), "caught exception when updating broker data", "error", err)
 		}
 
 		lastInvocation = time.Now()
@@ -110,7 +117,7 @@ func (s *controllerBasedSelector) updateBrokerData() error {
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Error("Unable to close response body. ", err)
+			loggerOrDefault(s.logger).Error(context.Background(), "Unable to close response body", "error", err)
 		}
 	}()
 	if resp.StatusCode == http.StatusOK {
@@ -122,10 +129,7 @@ func (s *controllerBasedSelector) updateBrokerData() error {
 		if err = decodeJSONWithNumber(bodyBytes, &c); err != nil {
 			return fmt.Errorf("an error occurred when decoding controller API response: %v", err)
 		}
-		s.rwMux.Lock()
-		s.allBrokerList = c.extractBrokerList()
-		s.tableBrokerMap = c.extractTableToBrokerMap()
-		s.rwMux.Unlock()
+		s.setBrokers(c.extractTableToBrokerMap(), c.extractBrokerList())
 		return nil
 	}
 	return fmt.Errorf("controller API returned HTTP status code %v", resp.StatusCode)
@@ -7,7 +7,10 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -268,3 +271,81 @@ func TestUpdateBrokerDataUnexpectedHTTPStatus(t *testing.T) {
 	fmt.Println(err.Error())
 	assert.True(t, strings.Contains(err.Error(), "returned HTTP status code 500"))
 }
+
+type countingHTTPClient struct {
+	requests atomic.Int32
+}
+
+func (c *countingHTTPClient) Do(_ *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"baseballStats":[{"port":8000,"host":"host1","instanceName":"Broker_host1_8000"}]}`)),
+	}, nil
+}
+
+func TestControllerBasedBrokerSelectorCloseStopsPolling(t *testing.T) {
+	client := &countingHTTPClient{}
+	s := &controllerBasedSelector{
+		config: &ControllerConfig{
+			ControllerAddress: "localhost:9000",
+			UpdateFreqMs:      10,
+		},
+		client: client,
+	}
+	assert.NoError(t, s.init())
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, s.Close())
+	// Let an in-flight refresh finish before sampling the request count.
+	time.Sleep(20 * time.Millisecond)
+	requests := client.requests.Load()
+	assert.Greater(t, requests, int32(1))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, requests, client.requests.Load())
+	assert.NoError(t, s.Close())
+}
+
+// recordingObserver records the notifications of a BrokerSelectorObserver.
+type recordingObserver struct {
+	mu           sync.Mutex
+	tableBrokers []map[string][]string
+	allBrokers   [][]string
+	errs         []error
+}
+
+func (o *recordingObserver) BrokersUpdated(tableBrokers map[string][]string, allBrokers []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tableBrokers = append(o.tableBrokers, tableBrokers)
+	o.allBrokers = append(o.allBrokers, allBrokers)
+}
+
+func (o *recordingObserver) BrokersRefreshFailed(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.errs = append(o.errs, err)
+}
+
+func TestControllerBasedBrokerSelectorObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	s := &controllerBasedSelector{
+		config:                   &ControllerConfig{ControllerAddress: "localhost:9000", UpdateFreqMs: 60000},
+		client:                   &countingHTTPClient{},
+		tableAwareBrokerSelector: tableAwareBrokerSelector{observer: observer},
+	}
+	assert.NoError(t, s.init())
+	defer s.Close()
+	assert.Equal(t, []map[string][]string{{"baseballStats": {"host1:8000"}}}, observer.tableBrokers)
+	assert.Equal(t, [][]string{{"host1:8000"}}, observer.allBrokers)
+	assert.Empty(t, observer.errs)
+
+	failing := &controllerBasedSelector{
+		config:                   &ControllerConfig{ControllerAddress: "localhost:9000"},
+		client:                   &MockHTTPClientFailure{err: errors.New("http client error")},
+		tableAwareBrokerSelector: tableAwareBrokerSelector{observer: observer},
+	}
+	assert.Error(t, failing.init())
+	assert.Len(t, observer.tableBrokers, 1)
+	assert.Len(t, observer.errs, 1)
+	assert.ErrorContains(t, observer.errs[0], "http client error")
+}
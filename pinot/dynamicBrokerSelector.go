@@ -1,6 +1,7 @@
 package pinot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -8,8 +9,6 @@ import (
 	"time"
 
 	zk "github.com/go-zookeeper/zk"
-
-	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -22,6 +21,7 @@ type ReadZNode func(path string) ([]byte, error)
 type zkClient interface {
 	Get(path string) ([]byte, *zk.Stat, error)
 	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	Close()
 }
 
 var zkConnect = func(servers []string, timeout time.Duration) (zkClient, <-chan zk.Event, error) {
@@ -63,6 +63,7 @@ func (s *dynamicBrokerSelector) init() error {
 		return fmt.Errorf("failed to set a watcher on ExternalView path: %s, error: %v", strings.Join(append(s.zkConfig.ZookeeperPath, s.externalViewZkPath), ""), err)
 	}
 	if err = s.refreshExternalView(); err != nil {
+		s.refreshFailed(err)
 		return err
 	}
 	go s.setupWatcher()
@@ -70,19 +71,41 @@ func (s *dynamicBrokerSelector) init() error {
 }
 
 func (s *dynamicBrokerSelector) setupWatcher() {
+	done := s.doneChan()
 	for {
-		ev := <-s.externalViewZnodeWatch
+		var ev zk.Event
+		select {
+		case <-done:
+			return
+		case ev = <-s.externalViewZnodeWatch:
+		}
 		if ev.Err != nil {
-			log.Error("GetW watcher error", ev.Err)
+			loggerOrDefault(s.logger).Error(context.Background(), "GetW watcher error", "error", ev.Err)
 		} else if ev.Type == zk.EventNodeDataChanged {
 			if err := s.refreshExternalView(); err != nil {
-				log.Errorf("Failed to refresh ExternalView, Error: %v\n", err)
+				s.refreshFailed(err)
+				loggerOrDefault(s.logger).Error(context.Background(), "Failed to refresh ExternalView", "error", err)
 			}
 		}
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-done:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
 }
 
+// Close stops watching the ExternalView and closes the Zookeeper session.
+func (s *dynamicBrokerSelector) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.doneChan())
+		if s.zkConn != nil {
+			s.zkConn.Close()
+		}
+	})
+	return nil
+}
+
 func (s *dynamicBrokerSelector) refreshExternalView() error {
 	if s.readZNode == nil {
 		return fmt.Errorf("no method defined to read from a ZNode")
@@ -95,11 +118,7 @@ func (s *dynamicBrokerSelector) refreshExternalView() error {
 	if err != nil {
 		return err
 	}
-	newTableBrokerMap, newAllBrokerList := generateNewBrokerMappingExternalView(ev)
-	s.rwMux.Lock()
-	s.tableBrokerMap = newTableBrokerMap
-	s.allBrokerList = newAllBrokerList
-	s.rwMux.Unlock()
+	s.setBrokers(generateNewBrokerMappingExternalView(ev))
 	return nil
 }
 
@@ -14,6 +14,7 @@ type fakeZkClient struct {
 	getErr   error
 	getWErr  error
 	watch    <-chan zk.Event
+	closed   bool
 }
 
 func (f *fakeZkClient) Get(_ string) ([]byte, *zk.Stat, error) {
@@ -24,6 +25,10 @@ func (f *fakeZkClient) GetW(_ string) ([]byte, *zk.Stat, <-chan zk.Event, error)
 	return nil, &zk.Stat{}, f.watch, f.getWErr
 }
 
+func (f *fakeZkClient) Close() {
+	f.closed = true
+}
+
 func TestExtractBrokers(t *testing.T) {
 	brokers := extractBrokers(map[string]string{
 		"BROKER_broker-1_1000": "ONLINE",
@@ -294,3 +299,41 @@ func TestMockUpdateEvent(t *testing.T) {
 	err = selector.refreshExternalView()
 	assert.EqualError(t, err, "erroReadZNode")
 }
+
+func TestCloseStopsWatcherAndClosesZk(t *testing.T) {
+	originalConnect := zkConnect
+	watch := make(chan zk.Event)
+	defer func() { zkConnect = originalConnect }()
+
+	fakeClient := &fakeZkClient{
+		getBytes: []byte(`{"id":"brokerResource","mapFields":{"baseballStats_OFFLINE":{"Broker_127.0.0.1_8000":"ONLINE"}}}`),
+		watch:    watch,
+	}
+	zkConnect = func(_ []string, _ time.Duration) (zkClient, <-chan zk.Event, error) {
+		return fakeClient, watch, nil
+	}
+
+	selector := &dynamicBrokerSelector{
+		zkConfig: &ZookeeperConfig{
+			ZookeeperPath:     []string{"localhost:2123"},
+			PathPrefix:        "/QuickStartCluster",
+			SessionTimeoutSec: 1,
+		},
+	}
+	assert.NoError(t, selector.init())
+
+	stopped := make(chan struct{})
+	go func() {
+		selector.setupWatcher()
+		close(stopped)
+	}()
+
+	assert.NoError(t, selector.Close())
+	assert.True(t, fakeClient.closed)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not stop after Close")
+	}
+	assert.NoError(t, selector.Close())
+}
@@ -0,0 +1,162 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrConnectionClosed is returned by queries issued on a Connection after Close was called.
+	ErrConnectionClosed = errors.New("pinot connection is closed")
+	// ErrNoBrokerAvailable is matched by errors returned when no broker can serve a query.
+	ErrNoBrokerAvailable = errors.New("no available broker found")
+	// ErrTableNotFound is matched by errors returned when the queried table is unknown,
+	// either to the broker selector or to Pinot itself.
+	ErrTableNotFound = errors.New("unable to find the table")
+	// ErrQueryTimeout is matched by errors returned when a query exceeds its deadline,
+	// either on the client side or on the Pinot side.
+	ErrQueryTimeout = errors.New("pinot query timed out")
+	// ErrNullValue is matched by errors returned by the typed accessors of ResultTable for null entries.
+	ErrNullValue = errors.New("null value")
+	// ErrColumnNotFound is matched by errors returned when looking up a column absent from a result table.
+	ErrColumnNotFound = errors.New("column not found")
+	// ErrArrowUnsupported is returned by QueryArrow when the connection does not use the gRPC
+	// transport with the ARROW encoding.
+	ErrArrowUnsupported = errors.New("arrow records require the grpc transport with ARROW encoding")
+	// ErrQueryNotFound is matched by errors returned by Cancel when no broker runs the query.
+	ErrQueryNotFound = errors.New("query not found")
+	// ErrCancelUnsupported is returned by Cancel when the connection does not use the HTTP transport,
+	// brokers only accepting cancellations over HTTP.
+	ErrCancelUnsupported = errors.New("query cancellation requires the http transport")
+)
+
+// Pinot query exception error codes, as reported in BrokerResponse.Exceptions.
+const (
+	JSONParsingErrorCode            = 100
+	SQLParsingErrorCode             = 150
+	AccessDeniedErrorCode           = 180
+	TableDoesNotExistErrorCode      = 190
+	TableIsDisabledErrorCode        = 191
+	QueryExecutionErrorCode         = 200
+	QueryCancellationErrorCode      = 205
+	ServerShuttingDownErrorCode     = 210
+	ServerOutOfCapacityErrorCode    = 211
+	ServerTableMissingErrorCode     = 230
+	ServerSegmentMissingErrorCode   = 235
+	QuerySchedulingTimeoutErrorCode = 240
+	ExecutionTimeoutErrorCode       = 250
+	BrokerTimeoutErrorCode          = 400
+	BrokerResourceMissingErrorCode  = 410
+	BrokerInstanceMissingErrorCode  = 420
+	BrokerRequestSendErrorCode      = 425
+	ServerNotRespondingErrorCode    = 427
+	TooManyRequestsErrorCode        = 429
+	InternalErrorCode               = 450
+	QueryValidationErrorCode        = 700
+	UnknownColumnErrorCode          = 710
+	QueryPlanningErrorCode          = 720
+	UnknownErrorCode                = 1000
+)
+
+// maxHTTPErrorBodySize caps the part of a failed broker response kept in a BrokerHTTPError.
+const maxHTTPErrorBodySize = 64 * 1024
+
+// BrokerHTTPError is returned when a broker answers a query with a non-200 HTTP status.
+type BrokerHTTPError struct {
+	// Broker is the address of the broker the query was sent to
+	Broker string
+	// StatusCode is the HTTP status code of the broker response
+	StatusCode int
+	// Body is the body of the broker response, truncated to 64KiB
+	Body string
+}
+
+func (e *BrokerHTTPError) Error() string {
+	msg := fmt.Sprintf("caught http exception when querying Pinot: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ", body: " + e.Body
+	}
+	return msg
+}
+
+// Is reports gateway and request timeouts as ErrQueryTimeout.
+func (e *BrokerHTTPError) Is(target error) bool {
+	if target == ErrQueryTimeout {
+		return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// QueryException is a Pinot exception reported in BrokerResponse.Exceptions, as an error.
+type QueryException struct {
+	// ErrorCode is the Pinot error code, one of the *ErrorCode constants
+	ErrorCode int
+	// Message is the exception message returned by Pinot
+	Message string
+}
+
+func (e *QueryException) Error() string {
+	return fmt.Sprintf("pinot query exception (errorCode %d): %s", e.ErrorCode, e.Message)
+}
+
+// Is maps Pinot error codes to the ErrTableNotFound and ErrQueryTimeout sentinels.
+func (e *QueryException) Is(target error) bool {
+	switch target {
+	case ErrTableNotFound:
+		return e.ErrorCode == TableDoesNotExistErrorCode || e.ErrorCode == BrokerResourceMissingErrorCode
+	case ErrQueryTimeout:
+		return e.ErrorCode == QuerySchedulingTimeoutErrorCode || e.ErrorCode == ExecutionTimeoutErrorCode ||
+			e.ErrorCode == BrokerTimeoutErrorCode
+	}
+	return false
+}
+
+// Err returns the exceptions of the response as an error, nil when there are none.
+// A single exception is returned as a *QueryException; several ones are joined with errors.Join,
+// so errors.As and errors.Is still match each of them.
+func (r *BrokerResponse) Err() error {
+	if r == nil || len(r.Exceptions) == 0 {
+		return nil
+	}
+	if len(r.Exceptions) == 1 {
+		return &QueryException{ErrorCode: r.Exceptions[0].ErrorCode, Message: r.Exceptions[0].Message}
+	}
+	errs := make([]error, 0, len(r.Exceptions))
+	for _, exception := range r.Exceptions {
+		errs = append(errs, &QueryException{ErrorCode: exception.ErrorCode, Message: exception.Message})
+	}
+	return errors.Join(errs...)
+}
+
+// timeoutError marks an error caused by an expired deadline so that it matches ErrQueryTimeout,
+// while still unwrapping to the original error such as context.DeadlineExceeded.
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return e.err.Error()
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrQueryTimeout
+}
+
+// markTimeout wraps err in a timeoutError when it was caused by an expired deadline,
+// either of the query context or of the transport (e.g. http.Client.Timeout).
+func markTimeout(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &timeoutError{err: err}
+	}
+	var netErr interface{ Timeout() bool }
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &timeoutError{err: err}
+	}
+	return err
+}
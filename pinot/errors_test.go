@@ -0,0 +1,112 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerHTTPError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &BrokerHTTPError{Broker: "localhost:8000", StatusCode: http.StatusGatewayTimeout, Body: "timeout"})
+	assert.EqualError(t, err, "wrapped: caught http exception when querying Pinot: 504 Gateway Timeout, body: timeout")
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+
+	var httpErr *BrokerHTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusGatewayTimeout, httpErr.StatusCode)
+
+	err = &BrokerHTTPError{StatusCode: http.StatusServiceUnavailable}
+	assert.EqualError(t, err, "caught http exception when querying Pinot: 503 Service Unavailable")
+	assert.False(t, errors.Is(err, ErrQueryTimeout))
+}
+
+func TestNewBrokerHTTPErrorReadFailure(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadGateway, Body: errReader(0)}
+	err := newBrokerHTTPError("localhost:8000", resp)
+	assert.Equal(t, "localhost:8000", err.Broker)
+	assert.Equal(t, http.StatusBadGateway, err.StatusCode)
+	assert.Equal(t, "(failed to read body: test read error)", err.Body)
+}
+
+func TestQueryExceptionIs(t *testing.T) {
+	assert.ErrorIs(t, &QueryException{ErrorCode: TableDoesNotExistErrorCode}, ErrTableNotFound)
+	assert.ErrorIs(t, &QueryException{ErrorCode: BrokerResourceMissingErrorCode}, ErrTableNotFound)
+	assert.ErrorIs(t, &QueryException{ErrorCode: ExecutionTimeoutErrorCode}, ErrQueryTimeout)
+	assert.ErrorIs(t, &QueryException{ErrorCode: BrokerTimeoutErrorCode}, ErrQueryTimeout)
+	assert.ErrorIs(t, &QueryException{ErrorCode: QuerySchedulingTimeoutErrorCode}, ErrQueryTimeout)
+
+	err := &QueryException{ErrorCode: SQLParsingErrorCode, Message: "bad sql"}
+	assert.EqualError(t, err, "pinot query exception (errorCode 150): bad sql")
+	assert.False(t, errors.Is(err, ErrTableNotFound))
+	assert.False(t, errors.Is(err, ErrQueryTimeout))
+}
+
+func TestBrokerResponseErr(t *testing.T) {
+	assert.NoError(t, (*BrokerResponse)(nil).Err())
+	assert.NoError(t, (&BrokerResponse{Exceptions: []Exception{}}).Err())
+
+	err := (&BrokerResponse{Exceptions: []Exception{{ErrorCode: 190, Message: "missing"}}}).Err()
+	var queryException *QueryException
+	require.True(t, errors.As(err, &queryException))
+	assert.Equal(t, 190, queryException.ErrorCode)
+	assert.Equal(t, "missing", queryException.Message)
+
+	err = (&BrokerResponse{Exceptions: []Exception{
+		{ErrorCode: SQLParsingErrorCode, Message: "bad sql"},
+		{ErrorCode: ExecutionTimeoutErrorCode, Message: "timed out"},
+	}}).Err()
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+	assert.False(t, errors.Is(err, ErrTableNotFound))
+	require.True(t, errors.As(err, &queryException))
+	assert.Equal(t, SQLParsingErrorCode, queryException.ErrorCode)
+}
+
+func TestMarkTimeout(t *testing.T) {
+	assert.ErrorIs(t, markTimeout(context.DeadlineExceeded), ErrQueryTimeout)
+	assert.ErrorIs(t, markTimeout(context.DeadlineExceeded), context.DeadlineExceeded)
+	assert.False(t, errors.Is(markTimeout(context.Canceled), ErrQueryTimeout))
+	assert.Equal(t, context.Canceled, markTimeout(context.Canceled))
+}
+
+func TestExceptionsAsErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[{"errorCode":190,"message":"TableDoesNotExistError"}],"numServersQueried":0}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+	resp, err := conn.ExecuteSQL("", "select * from missingTable")
+	require.NoError(t, err)
+	assert.Len(t, resp.Exceptions, 1)
+
+	resp, err = conn.With(WithExceptionsAsErrors()).ExecuteSQL("", "select * from missingTable")
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	require.NotNil(t, resp)
+	assert.Len(t, resp.Exceptions, 1)
+
+	conn, err = NewWithConfig(&ClientConfig{BrokerList: []string{ts.URL}, ExceptionsAsErrors: true})
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQL("", "select * from missingTable")
+	var queryException *QueryException
+	require.True(t, errors.As(err, &queryException))
+	assert.Equal(t, TableDoesNotExistErrorCode, queryException.ErrorCode)
+}
+
+func TestSelectorErrorsSurfaceThroughConnection(t *testing.T) {
+	conn := &Connection{
+		brokerSelector: &controllerBasedSelector{tableAwareBrokerSelector: tableAwareBrokerSelector{tableBrokerMap: map[string][]string{"myTable": {}}}},
+	}
+	_, err := conn.ExecuteSQL("unknownTable", "select 1")
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	_, err = conn.ExecuteSQL("myTable", "select 1")
+	assert.ErrorIs(t, err, ErrNoBrokerAvailable)
+}
@@ -8,9 +8,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
@@ -26,6 +29,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 
 	proto "github.com/startreedata/pinot-client-go/pinot/proto"
 )
@@ -44,6 +48,7 @@ var maxGrpcPayloadLength = int(^uint(0) >> 1)
 
 type grpcBrokerClientTransport struct {
 	config *GrpcConfig
+	pool   *grpcConnPool
 }
 
 func newGrpcBrokerClientTransport(config *GrpcConfig) (*grpcBrokerClientTransport, error) {
@@ -52,111 +57,228 @@ func newGrpcBrokerClientTransport(config *GrpcConfig) (*grpcBrokerClientTranspor
 	}
 	return &grpcBrokerClientTransport{
 		config: config,
+		pool:   newGrpcConnPool(config),
 	}, nil
 }
 
-func (t *grpcBrokerClientTransport) execute(brokerAddress string, query *Request) (*BrokerResponse, error) {
+func (t *grpcBrokerClientTransport) execute(ctx context.Context, brokerAddress string, query *Request) (*BrokerResponse, error) {
+	reader, err := t.submit(ctx, brokerAddress, query)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rows, err := reader.nextRows()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Join(err, reader.close())
+		}
+		reader.response.ResultTable.Rows = append(reader.response.ResultTable.Rows, rows...)
+	}
+	if err := reader.close(); err != nil {
+		return nil, err
+	}
+	return reader.response, nil
+}
+
+func (t *grpcBrokerClientTransport) stream(ctx context.Context, brokerAddress string, query *Request) (*RowStream, error) {
+	reader, err := t.submit(ctx, brokerAddress, query)
+	if err != nil {
+		return nil, err
+	}
+	return newRowStream(reader.response, reader.nextRows, reader.close), nil
+}
+
+func (t *grpcBrokerClientTransport) arrowEnabled() bool {
+	return strings.EqualFold(normalizeAlgorithm(t.config.Encoding, "", defaultGrpcEncoding), "ARROW")
+}
+
+func (t *grpcBrokerClientTransport) arrowRecords(ctx context.Context, brokerAddress string, query *Request) (*ArrowRecordReader, error) {
+	reader, err := t.submit(ctx, brokerAddress, query)
+	if err != nil {
+		return nil, err
+	}
+	var schema RespSchema
+	if reader.schema != nil {
+		schema = *reader.schema
+	}
+	allocator := t.config.ArrowAllocator
+	if allocator == nil {
+		allocator = memory.DefaultAllocator
+	}
+	return newArrowRecordReader(reader.response, schema, allocator, reader.nextArrowPayload, reader.close), nil
+}
+
+// submit sends the query to the broker and reads the metadata and schema blocks of the response.
+// The returned reader must be closed, which cancels the RPC if it is still running.
+func (t *grpcBrokerClientTransport) submit(ctx context.Context, brokerAddress string, query *Request) (*grpcBlockReader, error) {
 	address := normalizeGrpcAddress(brokerAddress)
-	ctx := context.Background()
+	var cancel context.CancelFunc
 	if t.config.Timeout > 0 {
-		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
-		defer cancel()
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
 	}
-	dialOptions, err := buildGrpcDialOptions(t.config)
+	conn, release, err := t.pool.get(ctx, address)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	//nolint:staticcheck // grpc.NewClient lacks context-based timeout semantics here.
-	conn, err := grpcDialContext(ctx, address, dialOptions...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial grpc broker %s: %w", address, err)
+	reader := &grpcBlockReader{
+		ctx: ctx,
+		close: func() error {
+			cancel()
+			release()
+			return nil
+		},
+		config: t.config,
 	}
-	defer func() {
-		if closeErr := conn.Close(); closeErr != nil {
-			_ = closeErr
-		}
-	}()
 
 	client := proto.NewPinotQueryBrokerClient(conn)
 	request := &proto.BrokerRequest{
 		Sql:      query.query,
-		Metadata: buildGrpcMetadata(t.config, query),
+		Metadata: buildGrpcMetadata(t.config, query, queryTimeout(ctx, t.config.Timeout)),
 	}
-	stream, err := client.Submit(ctx, request)
+	reader.stream, err = client.Submit(withOutgoingHeaders(ctx, query.headers), request)
 	if err != nil {
-		return nil, fmt.Errorf("grpc submit failed: %w", err)
+		return nil, errors.Join(fmt.Errorf("grpc submit failed: %w", contextError(ctx, err)), reader.close())
+	}
+	if err := reader.readHeader(); err != nil {
+		return nil, errors.Join(err, reader.close())
 	}
+	return reader, nil
+}
 
-	var response *BrokerResponse
-	var schema *RespSchema
-	for {
-		block, recvErr := stream.Recv()
-		if recvErr == io.EOF {
-			break
-		}
-		if recvErr != nil {
-			return nil, fmt.Errorf("grpc response error: %w", recvErr)
-		}
-		if response == nil {
-			var brokerResponse BrokerResponse
-			if decodeErr := decodeJSONWithNumber(block.Payload, &brokerResponse); decodeErr != nil {
-				return nil, fmt.Errorf("failed to decode grpc metadata block: %w", decodeErr)
-			}
-			response = &brokerResponse
-			continue
-		}
-		if schema == nil {
-			decodedSchema, schemaErr := decodeDataSchema(block.Payload)
-			if schemaErr != nil {
-				return nil, fmt.Errorf("failed to decode grpc schema block: %w", schemaErr)
-			}
-			schema = &decodedSchema
-			if response.ResultTable == nil {
-				response.ResultTable = &ResultTable{
-					DataSchema: *schema,
-					Rows:       [][]interface{}{},
-				}
-			} else {
-				response.ResultTable.DataSchema = *schema
-			}
-			continue
-		}
+// grpcBlockReader decodes the blocks of a gRPC broker response one at a time. Blocks are only
+// received from the stream when requested, so gRPC flow control applies backpressure to the broker.
+type grpcBlockReader struct {
+	ctx      context.Context
+	stream   proto.PinotQueryBroker_SubmitClient
+	close    func() error
+	config   *GrpcConfig
+	response *BrokerResponse
+	schema   *RespSchema
+}
 
-		rowSize, err := parseRowSize(block.Metadata)
-		if err != nil {
-			return nil, err
-		}
-		encoding := normalizeAlgorithm(block.Metadata["encoding"], t.config.Encoding, defaultGrpcEncoding)
-		compression := normalizeAlgorithm(block.Metadata["compression"], t.config.Compression, defaultGrpcCompression)
-		payload, err := decompressGrpcPayload(block.Payload, compression)
-		if err != nil {
-			return nil, err
-		}
+// readHeader reads the metadata block and, when the response has results, the schema block.
+func (r *grpcBlockReader) readHeader() error {
+	block, err := r.stream.Recv()
+	if err == io.EOF {
+		return fmt.Errorf("no grpc response payload received")
+	}
+	if err != nil {
+		return fmt.Errorf("grpc response error: %w", contextError(r.ctx, err))
+	}
+	var brokerResponse BrokerResponse
+	if decodeErr := decodeJSONWithNumber(block.Payload, &brokerResponse); decodeErr != nil {
+		return fmt.Errorf("failed to decode grpc metadata block: %w", decodeErr)
+	}
+	r.response = &brokerResponse
 
-		var rows [][]interface{}
-		switch strings.ToUpper(encoding) {
-		case "JSON":
-			decodedRows, decodeErr := decodeJSONRows(payload, rowSize)
-			if decodeErr != nil {
-				return nil, decodeErr
-			}
-			rows = decodedRows
-		case "ARROW":
-			decodedRows, decodeErr := decodeArrowRows(payload, *schema)
-			if decodeErr != nil {
-				return nil, decodeErr
-			}
-			rows = decodedRows
-		default:
-			return nil, fmt.Errorf("unsupported grpc encoding: %s", encoding)
+	block, err = r.stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("grpc response error: %w", contextError(r.ctx, err))
+	}
+	decodedSchema, schemaErr := decodeDataSchema(block.Payload)
+	if schemaErr != nil {
+		return fmt.Errorf("failed to decode grpc schema block: %w", schemaErr)
+	}
+	r.schema = &decodedSchema
+	if r.response.ResultTable == nil {
+		r.response.ResultTable = &ResultTable{
+			DataSchema: decodedSchema,
+			Rows:       [][]interface{}{},
 		}
-		response.ResultTable.Rows = append(response.ResultTable.Rows, rows...)
+	} else {
+		r.response.ResultTable.DataSchema = decodedSchema
+	}
+	r.response.ResultTable.indexColumns()
+	return nil
+}
+
+// nextRows receives and decodes the next data block, returning io.EOF once the response is complete.
+func (r *grpcBlockReader) nextRows() ([][]interface{}, error) {
+	block, err := r.nextBlock()
+	if err != nil {
+		return nil, err
+	}
+	rowSize, err := parseRowSize(block.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	payload, encoding, err := r.decompress(block)
+	if err != nil {
+		return nil, err
 	}
-	if response == nil {
-		return nil, fmt.Errorf("no grpc response payload received")
+
+	switch strings.ToUpper(encoding) {
+	case "JSON":
+		return decodeJSONRows(payload, rowSize)
+	case "ARROW":
+		return decodeArrowRows(payload, *r.schema)
+	default:
+		return nil, fmt.Errorf("unsupported grpc encoding: %s", encoding)
 	}
-	return response, nil
+}
+
+// nextArrowPayload receives the next data block, returning its Arrow IPC stream payload,
+// or io.EOF once the response is complete.
+func (r *grpcBlockReader) nextArrowPayload() ([]byte, error) {
+	block, err := r.nextBlock()
+	if err != nil {
+		return nil, err
+	}
+	payload, encoding, err := r.decompress(block)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(encoding, "ARROW") {
+		return nil, fmt.Errorf("%w: got %s encoded grpc block", ErrArrowUnsupported, encoding)
+	}
+	return payload, nil
+}
+
+// nextBlock receives the next data block, returning io.EOF once the response is complete.
+func (r *grpcBlockReader) nextBlock() (*proto.BrokerResponse, error) {
+	if r.schema == nil {
+		return nil, io.EOF
+	}
+	block, err := r.stream.Recv()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("grpc response error: %w", contextError(r.ctx, err))
+	}
+	return block, nil
+}
+
+// decompress returns the decompressed payload of a data block and its encoding.
+func (r *grpcBlockReader) decompress(block *proto.BrokerResponse) ([]byte, string, error) {
+	encoding := normalizeAlgorithm(block.Metadata["encoding"], r.config.Encoding, defaultGrpcEncoding)
+	compression := normalizeAlgorithm(block.Metadata["compression"], r.config.Compression, defaultGrpcCompression)
+	payload, err := decompressGrpcPayload(block.Payload, compression)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, encoding, nil
+}
+
+func (t *grpcBrokerClientTransport) close() error {
+	return t.pool.close()
+}
+
+// contextError prefers the context error over the gRPC status error once the context is done,
+// so callers can match cancellation and deadlines with errors.Is.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
 }
 
 func normalizeGrpcAddress(address string) string {
@@ -165,11 +287,14 @@ func normalizeGrpcAddress(address string) string {
 	return trimmed
 }
 
-func buildGrpcMetadata(config *GrpcConfig, query *Request) map[string]string {
+func buildGrpcMetadata(config *GrpcConfig, query *Request, timeout time.Duration) map[string]string {
 	metadata := map[string]string{}
 	for k, v := range config.ExtraMetadata {
 		metadata[k] = v
 	}
+	for k, v := range query.headers {
+		metadata[k] = v
+	}
 	blockRowSize := config.BlockRowSize
 	if blockRowSize <= 0 {
 		blockRowSize = defaultGrpcBlockRowSize
@@ -182,7 +307,7 @@ func buildGrpcMetadata(config *GrpcConfig, query *Request) map[string]string {
 	compression := normalizeAlgorithm(config.Compression, "", defaultGrpcCompression)
 	metadata["compression"] = strings.ToUpper(compression)
 
-	queryOptions := buildGrpcQueryOptions(query, config.Timeout)
+	queryOptions := buildQueryOptions(query, timeout)
 	if queryOptions != "" {
 		metadata["queryOptions"] = queryOptions
 	}
@@ -192,24 +317,17 @@ func buildGrpcMetadata(config *GrpcConfig, query *Request) map[string]string {
 	return metadata
 }
 
-func buildGrpcQueryOptions(query *Request, timeout time.Duration) string {
-	queryOptions := ""
-	if query.queryFormat == "sql" {
-		queryOptions = "groupByMode=sql;responseFormat=sql"
-	}
-	if query.useMultistageEngine {
-		if queryOptions != "" {
-			queryOptions += ";"
-		}
-		queryOptions += "useMultistageEngine=true"
+// withOutgoingHeaders attaches the headers of a request to the gRPC metadata of the call, where gRPC
+// interceptors and trace context propagation look for them, in addition to the broker request metadata.
+func withOutgoingHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
 	}
-	if timeout > 0 {
-		if queryOptions != "" {
-			queryOptions += ";"
-		}
-		queryOptions += fmt.Sprintf("timeoutMs=%d", timeout.Milliseconds())
+	pairs := make([]string, 0, 2*len(headers))
+	for k, v := range headers {
+		pairs = append(pairs, k, v)
 	}
-	return queryOptions
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
 }
 
 func normalizeAlgorithm(primary string, fallback string, defaultValue string) string {
@@ -245,9 +363,13 @@ func buildGrpcDialOptions(config *GrpcConfig) ([]grpc.DialOption, error) {
 	} else {
 		creds = insecure.NewCredentials()
 	}
-	return []grpc.DialOption{
+	options := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
-	}, nil
+	}
+	if config.IdleTimeout > 0 {
+		options = append(options, grpc.WithIdleTimeout(config.IdleTimeout))
+	}
+	return append(options, buildGrpcKeepAliveOptions(config)...), nil
 }
 
 func parseRowSize(metadata map[string]string) (int, error) {
@@ -404,7 +526,13 @@ func readArrowValue(column arrow.Array, columnType string, rowIdx int) (interfac
 			return nil, fmt.Errorf("expected DOUBLE column")
 		}
 		return json.Number(strconv.FormatFloat(floatCol.Value(rowIdx), 'f', -1, 64)), nil
-	case "TIMESTAMP", "STRING", "BYTES", "BIG_DECIMAL", "JSON", "OBJECT":
+	case "TIMESTAMP":
+		return arrowTimestampValue(column, rowIdx)
+	case "BYTES":
+		return arrowBytesString(column, rowIdx)
+	case "BIG_DECIMAL":
+		return arrowDecimalValue(column, rowIdx)
+	case "STRING", "JSON", "OBJECT":
 		stringCol, ok := column.(*array.String)
 		if !ok {
 			return nil, fmt.Errorf("expected STRING column")
@@ -448,12 +576,24 @@ func readArrowValue(column arrow.Array, columnType string, rowIdx int) (interfac
 			return nil, fmt.Errorf("expected DOUBLE_ARRAY column")
 		}
 		return decodeArrowDoubleList(listCol, rowIdx)
-	case "TIMESTAMP_ARRAY", "STRING_ARRAY", "BYTES_ARRAY":
+	case "STRING_ARRAY":
 		listCol, ok := column.(*array.List)
 		if !ok {
 			return nil, fmt.Errorf("expected STRING_ARRAY column")
 		}
 		return decodeArrowStringList(listCol, rowIdx)
+	case "TIMESTAMP_ARRAY":
+		listCol, ok := column.(*array.List)
+		if !ok {
+			return nil, fmt.Errorf("expected TIMESTAMP_ARRAY column")
+		}
+		return decodeArrowTimestampList(listCol, rowIdx)
+	case "BYTES_ARRAY":
+		listCol, ok := column.(*array.List)
+		if !ok {
+			return nil, fmt.Errorf("expected BYTES_ARRAY column")
+		}
+		return decodeArrowFormattedList(listCol, rowIdx, arrowBytesString)
 	default:
 		stringCol, ok := column.(*array.String)
 		if !ok {
@@ -565,6 +705,119 @@ func decodeArrowStringList(list *array.List, rowIdx int) ([]string, error) {
 	return output, nil
 }
 
+// decodeArrowFormattedList decodes a list of values formatted like the JSON encoding of the broker does.
+func decodeArrowFormattedList(list *array.List, rowIdx int, format func(arrow.Array, int) (string, error)) ([]string, error) {
+	if list.IsNull(rowIdx) {
+		return nil, nil
+	}
+	start, end := list.ValueOffsets(rowIdx)
+	values := list.ListValues()
+	output := make([]string, 0, end-start)
+	for i := int(start); i < int(end); i++ {
+		value, err := format(values, i)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, value)
+	}
+	return output, nil
+}
+
+// arrowTimestampValue returns a TIMESTAMP value: Pinot timestamp strings are kept as such, like in the
+// JSON encoding of the broker, while Arrow timestamps and epoch milliseconds are decoded to times.
+func arrowTimestampValue(column arrow.Array, rowIdx int) (interface{}, error) {
+	if col, ok := column.(*array.String); ok {
+		return col.Value(rowIdx), nil
+	}
+	return arrowTime(column, rowIdx)
+}
+
+// arrowTime decodes an Arrow timestamp or epoch milliseconds value to a time in UTC.
+func arrowTime(column arrow.Array, rowIdx int) (time.Time, error) {
+	switch col := column.(type) {
+	case *array.Timestamp:
+		timestampType, ok := col.DataType().(*arrow.TimestampType)
+		if !ok {
+			return time.Time{}, fmt.Errorf("expected TIMESTAMP column, got %s", col.DataType())
+		}
+		return col.Value(rowIdx).ToTime(timestampType.Unit), nil
+	case *array.Int64:
+		return time.UnixMilli(col.Value(rowIdx)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("expected TIMESTAMP column, got %s", column.DataType())
+	}
+}
+
+// decodeArrowTimestampList decodes a TIMESTAMP_ARRAY value as strings when the broker sends Pinot
+// timestamp strings, and as times otherwise.
+func decodeArrowTimestampList(list *array.List, rowIdx int) (interface{}, error) {
+	if _, ok := list.ListValues().(*array.String); ok {
+		return decodeArrowStringList(list, rowIdx)
+	}
+	if list.IsNull(rowIdx) {
+		return nil, nil
+	}
+	start, end := list.ValueOffsets(rowIdx)
+	values := list.ListValues()
+	output := make([]time.Time, 0, end-start)
+	for i := int(start); i < int(end); i++ {
+		value, err := arrowTime(values, i)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, value)
+	}
+	return output, nil
+}
+
+// arrowBytesString returns a BYTES value as the hex string of the JSON encoding of the broker.
+func arrowBytesString(column arrow.Array, rowIdx int) (string, error) {
+	switch col := column.(type) {
+	case *array.String:
+		return col.Value(rowIdx), nil
+	case interface{ Value(int) []byte }:
+		return hex.EncodeToString(col.Value(rowIdx)), nil
+	default:
+		return "", fmt.Errorf("expected BYTES column, got %s", column.DataType())
+	}
+}
+
+// arrowDecimalValue returns a BIG_DECIMAL value: decimal strings are kept as such, like in the JSON
+// encoding of the broker, while Arrow decimals are decoded to exact rational numbers.
+func arrowDecimalValue(column arrow.Array, rowIdx int) (interface{}, error) {
+	switch col := column.(type) {
+	case *array.String:
+		return col.Value(rowIdx), nil
+	case *array.Decimal128:
+		decimalType, ok := col.DataType().(*arrow.Decimal128Type)
+		if !ok {
+			return nil, fmt.Errorf("expected BIG_DECIMAL column, got %s", col.DataType())
+		}
+		return decimalRat(col.Value(rowIdx).BigInt(), decimalType.Scale), nil
+	case *array.Decimal256:
+		decimalType, ok := col.DataType().(*arrow.Decimal256Type)
+		if !ok {
+			return nil, fmt.Errorf("expected BIG_DECIMAL column, got %s", col.DataType())
+		}
+		return decimalRat(col.Value(rowIdx).BigInt(), decimalType.Scale), nil
+	default:
+		return nil, fmt.Errorf("expected BIG_DECIMAL column, got %s", column.DataType())
+	}
+}
+
+// decimalRat returns unscaled * 10^-scale.
+func decimalRat(unscaled *big.Int, scale int32) *big.Rat {
+	exponent := int64(scale)
+	if exponent < 0 {
+		exponent = -exponent
+	}
+	power := new(big.Int).Exp(big.NewInt(10), big.NewInt(exponent), nil)
+	if scale < 0 {
+		return new(big.Rat).SetInt(new(big.Int).Mul(unscaled, power))
+	}
+	return new(big.Rat).SetFrac(unscaled, power)
+}
+
 func decodeMap(payload []byte) (map[string]interface{}, error) {
 	reader := bytes.NewReader(payload)
 	var size int32
@@ -23,6 +23,7 @@ import (
 
 	"github.com/apache/arrow/go/v15/arrow"
 	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/decimal128"
 	"github.com/apache/arrow/go/v15/arrow/ipc"
 	"github.com/apache/arrow/go/v15/arrow/memory"
 	"github.com/golang/snappy"
@@ -31,18 +32,23 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	proto "github.com/startreedata/pinot-client-go/pinot/proto"
 )
 
 type mockPinotQueryBrokerServer struct {
 	proto.UnimplementedPinotQueryBrokerServer
-	responses   []*proto.BrokerResponse
-	lastRequest *proto.BrokerRequest
+	responses    []*proto.BrokerResponse
+	lastRequest  *proto.BrokerRequest
+	lastMetadata metadata.MD
 }
 
 func (s *mockPinotQueryBrokerServer) Submit(req *proto.BrokerRequest, stream proto.PinotQueryBroker_SubmitServer) error {
 	s.lastRequest = req
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		s.lastMetadata = md
+	}
 	for _, resp := range s.responses {
 		if err := stream.Send(resp); err != nil {
 			return err
@@ -120,7 +126,7 @@ func TestGrpcBrokerClientTransportJSON(t *testing.T) {
 	})
 	assert.NoError(t, err)
 
-	resp, err := transport.execute(listener.Addr().String(), &Request{
+	resp, err := transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat:         "sql",
 		query:               "select * from baseballStats limit 2",
 		useMultistageEngine: true,
@@ -169,7 +175,7 @@ func TestGrpcBrokerClientTransportArrow(t *testing.T) {
 	})
 	assert.NoError(t, err)
 
-	resp, err := transport.execute(listener.Addr().String(), &Request{
+	resp, err := transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 2",
 	})
@@ -194,7 +200,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
@@ -212,7 +218,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	defer server.Stop()
 
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
@@ -230,7 +236,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	defer server.Stop()
 
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
@@ -248,7 +254,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	defer server.Stop()
 
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
@@ -265,7 +271,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	defer server.Stop()
 
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
@@ -274,19 +280,19 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	defer server.Stop()
 
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
 	server, listener = startGrpcErrorServer(t, nil, errors.New("submit failed"))
 	defer server.Stop()
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
 	server, listener = startGrpcErrorServer(t, []*proto.BrokerResponse{{Payload: []byte(`{"exceptions":[]}`)}}, errors.New("stream failed"))
 	defer server.Stop()
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	transport, err = newGrpcBrokerClientTransport(&GrpcConfig{
@@ -297,7 +303,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	_, err = transport.execute("bad:address", &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), "bad:address", &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	transport, err = newGrpcBrokerClientTransport(&GrpcConfig{
@@ -311,7 +317,7 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 		},
 	})
 	require.NoError(t, err)
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 
 	server.Stop()
@@ -324,10 +330,57 @@ func TestGrpcBrokerClientTransportErrors(t *testing.T) {
 		},
 	})
 	defer server.Stop()
-	_, err = transport.execute(listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 }
 
+type blockingPinotQueryBrokerServer struct {
+	proto.UnimplementedPinotQueryBrokerServer
+	requests chan *proto.BrokerRequest
+}
+
+func (s *blockingPinotQueryBrokerServer) Submit(req *proto.BrokerRequest, stream proto.PinotQueryBroker_SubmitServer) error {
+	s.requests <- req
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+func TestGrpcBrokerClientTransportContextCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	blockingServer := &blockingPinotQueryBrokerServer{requests: make(chan *proto.BrokerRequest, 1)}
+	proto.RegisterPinotQueryBrokerServer(server, blockingServer)
+	go func() {
+		if serveErr := server.Serve(listener); serveErr != nil && !errors.Is(serveErr, grpc.ErrServerStopped) {
+			assert.NoError(t, serveErr)
+		}
+	}()
+	defer server.Stop()
+
+	transport, err := newGrpcBrokerClientTransport(&GrpcConfig{
+		Encoding:    "JSON",
+		Compression: "NONE",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	go func() {
+		req := <-blockingServer.requests
+		assert.Contains(t, req.Metadata["queryOptions"], "timeoutMs=")
+		cancel()
+	}()
+	_, err = transport.execute(ctx, listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	deadlineCtx, deadlineCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer deadlineCancel()
+	go func() { <-blockingServer.requests }()
+	_, err = transport.execute(deadlineCtx, listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
 func TestGrpcBrokerClientTransportDialError(t *testing.T) {
 	transport, err := newGrpcBrokerClientTransport(&GrpcConfig{
 		Encoding:     "JSON",
@@ -343,7 +396,7 @@ func TestGrpcBrokerClientTransportDialError(t *testing.T) {
 	}
 	t.Cleanup(func() { grpcDialContext = original })
 
-	_, err = transport.execute("localhost:1234", &Request{queryFormat: "sql", query: "select 1"})
+	_, err = transport.execute(context.Background(), "localhost:1234", &Request{queryFormat: "sql", query: "select 1"})
 	assert.Error(t, err)
 }
 
@@ -428,7 +481,7 @@ func TestBuildGrpcMetadataDefaults(t *testing.T) {
 		queryFormat:         "sql",
 		useMultistageEngine: true,
 		trace:               true,
-	})
+	}, 3*time.Second)
 
 	assert.Equal(t, "10000", metadata["blockRowSize"])
 	assert.Equal(t, "JSON", metadata["encoding"])
@@ -440,8 +493,8 @@ func TestBuildGrpcMetadataDefaults(t *testing.T) {
 	assert.Equal(t, "true", metadata["trace"])
 }
 
-func TestBuildGrpcQueryOptionsNonSQL(t *testing.T) {
-	options := buildGrpcQueryOptions(&Request{
+func TestBuildQueryOptionsNonSQL(t *testing.T) {
+	options := buildQueryOptions(&Request{
 		queryFormat:         "pql",
 		useMultistageEngine: true,
 	}, 0)
@@ -1253,6 +1306,189 @@ func TestReadArrowValueSuccess(t *testing.T) {
 	assert.Equal(t, "value", val)
 }
 
+func TestDecodeArrowRowsPhysicalTypes(t *testing.T) {
+	allocator := memory.NewGoAllocator()
+	timestampType := &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}
+	decimalType := &arrow.Decimal128Type{Precision: 10, Scale: 2}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: timestampType},
+		{Name: "raw", Type: arrow.BinaryTypes.Binary},
+		{Name: "price", Type: decimalType},
+		{Name: "tsList", Type: arrow.ListOf(timestampType)},
+		{Name: "rawList", Type: arrow.ListOf(arrow.BinaryTypes.Binary)},
+	}, nil)
+	builder := array.NewRecordBuilder(allocator, schema)
+	defer builder.Release()
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC)
+	builder.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(ts.UnixMilli()))
+	builder.Field(1).(*array.BinaryBuilder).Append([]byte{0x0a, 0x0b})
+	builder.Field(2).(*array.Decimal128Builder).Append(decimal128.FromI64(-1230))
+	tsList := builder.Field(3).(*array.ListBuilder)
+	tsList.Append(true)
+	tsList.ValueBuilder().(*array.TimestampBuilder).Append(arrow.Timestamp(ts.Truncate(time.Second).UnixMilli()))
+	rawList := builder.Field(4).(*array.ListBuilder)
+	rawList.Append(true)
+	rawList.ValueBuilder().(*array.BinaryBuilder).Append([]byte{0xff})
+	record := builder.NewRecord()
+	defer record.Release()
+
+	buf := &bytes.Buffer{}
+	writer := ipc.NewWriter(buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	pinotSchema := RespSchema{
+		ColumnNames:     []string{"ts", "raw", "price", "tsList", "rawList"},
+		ColumnDataTypes: []string{"TIMESTAMP", "BYTES", "BIG_DECIMAL", "TIMESTAMP_ARRAY", "BYTES_ARRAY"},
+	}
+	arrowRows, err := decodeArrowRows(buf.Bytes(), pinotSchema)
+	require.NoError(t, err)
+	require.Len(t, arrowRows, 1)
+
+	assert.Equal(t, ts, arrowRows[0][0])
+	assert.Equal(t, "0a0b", arrowRows[0][1])
+	assert.Equal(t, big.NewRat(-123, 10), arrowRows[0][2])
+	assert.Equal(t, []time.Time{ts.Truncate(time.Second)}, arrowRows[0][3])
+	assert.Equal(t, []string{"ff"}, arrowRows[0][4])
+
+	// The same values encoded as JSON by a broker in UTC+8, which formats timestamps in its time zone.
+	jsonRows, err := decodeJSONRows(encodeJSONRowBlock(t, [][]interface{}{
+		{"2024-01-02 11:04:05.123", "0a0b", "-12.30", []string{"2024-01-02 11:04:05.0"}, []string{"ff"}},
+	}), 1)
+	require.NoError(t, err)
+	arrowTable := ResultTable{DataSchema: pinotSchema, Rows: arrowRows}
+	jsonTable := ResultTable{DataSchema: pinotSchema, Rows: jsonRows}
+	brokerZone := time.FixedZone("UTC+8", 8*60*60)
+	for _, table := range []ResultTable{arrowTable, jsonTable} {
+		tsValue, tsErr := table.GetTimeIn(0, 0, brokerZone)
+		require.NoError(t, tsErr)
+		assert.True(t, ts.Equal(tsValue))
+		raw, rawErr := table.GetBytes(0, 1)
+		require.NoError(t, rawErr)
+		assert.Equal(t, []byte{0x0a, 0x0b}, raw)
+		price, priceErr := table.GetBigDecimal(0, 2)
+		require.NoError(t, priceErr)
+		assert.Equal(t, "-12.3", formatBigRat(price))
+	}
+	assert.Equal(t, "2024-01-02 03:04:05.123", arrowTable.GetString(0, 0))
+	assert.Equal(t, "-12.3", arrowTable.GetString(0, 2))
+	times, err := arrowTable.GetTimestampArray(0, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{ts.Truncate(time.Second)}, times)
+}
+
+func TestDecodeArrowRowsNulls(t *testing.T) {
+	allocator := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "scores", Type: arrow.ListOf(arrow.PrimitiveTypes.Int64), Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(allocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int32Builder).AppendValues([]int32{1, 0}, []bool{true, false})
+	builder.Field(1).(*array.StringBuilder).AppendValues([]string{"", "b"}, []bool{false, true})
+	scores := builder.Field(2).(*array.ListBuilder)
+	scores.AppendNull()
+	scores.Append(true)
+	scores.ValueBuilder().(*array.Int64Builder).Append(3)
+	record := builder.NewRecord()
+	defer record.Release()
+
+	buf := &bytes.Buffer{}
+	writer := ipc.NewWriter(buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+	pinotSchema := RespSchema{
+		ColumnNames:     []string{"id", "name", "scores"},
+		ColumnDataTypes: []string{"INT", "STRING", "LONG_ARRAY"},
+	}
+	arrowRows, err := decodeArrowRows(buf.Bytes(), pinotSchema)
+	require.NoError(t, err)
+	jsonRows, err := decodeJSONRows(encodeJSONRowBlock(t, [][]interface{}{{1, nil, nil}, {nil, "b", []int64{3}}}), 2)
+	require.NoError(t, err)
+
+	arrowTable := ResultTable{DataSchema: pinotSchema, Rows: arrowRows}
+	jsonTable := ResultTable{DataSchema: pinotSchema, Rows: jsonRows}
+	for row := 0; row < 2; row++ {
+		for col := 0; col < 3; col++ {
+			assert.Equal(t, jsonTable.IsNull(row, col), arrowTable.IsNull(row, col), "row %d, column %d", row, col)
+		}
+	}
+	assert.True(t, arrowTable.IsNull(0, 1))
+	assert.True(t, arrowTable.IsNull(0, 2))
+	assert.True(t, arrowTable.IsNull(1, 0))
+	assert.Equal(t, jsonTable.GetNullString(1, 1), arrowTable.GetNullString(1, 1))
+}
+
+func TestReadArrowValuePhysicalTypeErrors(t *testing.T) {
+	allocator := memory.NewGoAllocator()
+	builder := array.NewFloat64Builder(allocator)
+	defer builder.Release()
+	builder.Append(1.5)
+	floats := builder.NewArray()
+	defer floats.Release()
+	for _, columnType := range []string{"TIMESTAMP", "BYTES", "BIG_DECIMAL"} {
+		_, err := readArrowValue(floats, columnType, 0)
+		assert.ErrorContains(t, err, "expected "+columnType+" column", columnType)
+	}
+
+	listBuilder := array.NewListBuilder(allocator, arrow.PrimitiveTypes.Float64)
+	defer listBuilder.Release()
+	listBuilder.Append(true)
+	listBuilder.ValueBuilder().(*array.Float64Builder).Append(1.5)
+	listBuilder.AppendNull()
+	list := listBuilder.NewArray()
+	defer list.Release()
+	_, err := readArrowValue(list, "TIMESTAMP_ARRAY", 0)
+	assert.Error(t, err)
+	val, err := readArrowValue(list, "BYTES_ARRAY", 1)
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+}
+
+func TestArrowTime(t *testing.T) {
+	allocator := memory.NewGoAllocator()
+	builder := array.NewInt64Builder(allocator)
+	defer builder.Release()
+	builder.Append(1700000000000)
+	millis := builder.NewArray()
+	defer millis.Release()
+	val, err := arrowTimestampValue(millis, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1700000000000).UTC(), val)
+
+	timestampBuilder := array.NewTimestampBuilder(allocator, &arrow.TimestampType{Unit: arrow.Microsecond})
+	defer timestampBuilder.Release()
+	timestampBuilder.Append(arrow.Timestamp(1700000000000001))
+	micros := timestampBuilder.NewArray()
+	defer micros.Release()
+	val, err = arrowTimestampValue(micros, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 1000).UTC(), val)
+
+	assert.Equal(t, "2023-11-14 22:13:20.000001", formatPinotTimestamp(time.Unix(1700000000, 1000).UTC()))
+}
+
+func TestDecimalRat(t *testing.T) {
+	tests := []struct {
+		unscaled int64
+		scale    int32
+		expected string
+	}{
+		{12345, 2, "123.45"},
+		{-5, 3, "-0.005"},
+		{0, 2, "0"},
+		{42, 0, "42"},
+		{42, -2, "4200"},
+		{0, -2, "0"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatBigRat(decimalRat(big.NewInt(tt.unscaled), tt.scale)))
+	}
+	assert.Equal(t, "0.3333333333333333333333333333333333", formatBigRat(big.NewRat(1, 3)))
+}
+
 func TestDecodeArrowRowsError(t *testing.T) {
 	allocator := memory.NewGoAllocator()
 	field := arrow.Field{Name: "id", Type: arrow.BinaryTypes.String}
@@ -1303,7 +1539,7 @@ func TestExecuteSchemaResultTableBranch(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	resp, err := transport.execute(listener.Addr().String(), &Request{
+	resp, err := transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -1335,7 +1571,7 @@ func TestExecuteUsesConfigDefaults(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	resp, err := transport.execute(listener.Addr().String(), &Request{
+	resp, err := transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -1368,7 +1604,7 @@ func TestExecuteRowSizeZero(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	resp, err := transport.execute(listener.Addr().String(), &Request{
+	resp, err := transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -1406,7 +1642,7 @@ func TestExecuteDecodeJSONRowsError(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	_, err = transport.execute(listener.Addr().String(), &Request{
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -1438,7 +1674,7 @@ func TestExecuteDecodeArrowRowsError(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	_, err = transport.execute(listener.Addr().String(), &Request{
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -0,0 +1,200 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	defaultGrpcPoolSize         = 1
+	defaultGrpcIdleTimeout      = 10 * time.Minute
+	defaultGrpcKeepAliveTimeout = 20 * time.Second
+	// grpcPoolSweepInterval is the minimum time between two evictions of idle and removed brokers
+	grpcPoolSweepInterval = 10 * time.Second
+)
+
+var errGrpcPoolClosed = errors.New("grpc connection pool is closed")
+
+// grpcBrokerConns are the pooled connections to a broker.
+type grpcBrokerConns struct {
+	conns    []*grpc.ClientConn
+	next     int
+	inFlight int
+	lastUsed time.Time
+}
+
+// grpcConnPool keeps a fixed number of gRPC connections per broker, so that queries reuse
+// established HTTP/2 connections instead of paying a TCP and TLS handshake each.
+// Connections of brokers idle for longer than the idle timeout, or no longer listed by the
+// broker selector, are closed on the next sweep.
+type grpcConnPool struct {
+	config        *GrpcConfig
+	mu            sync.Mutex
+	brokers       map[string]*grpcBrokerConns
+	closed        bool
+	lastSweep     time.Time
+	sweepInterval time.Duration
+	now           func() time.Time
+	// knownBrokers lists the brokers of the broker selector, nil when unknown
+	knownBrokers func() ([]string, error)
+	logger       Logger
+}
+
+func newGrpcConnPool(config *GrpcConfig) *grpcConnPool {
+	return &grpcConnPool{
+		config:        config,
+		brokers:       make(map[string]*grpcBrokerConns),
+		sweepInterval: grpcPoolSweepInterval,
+		now:           time.Now,
+	}
+}
+
+func (p *grpcConnPool) size() int {
+	if p.config.PoolSize > 0 {
+		return p.config.PoolSize
+	}
+	return defaultGrpcPoolSize
+}
+
+func (p *grpcConnPool) idleTimeout() time.Duration {
+	if p.config.IdleTimeout > 0 {
+		return p.config.IdleTimeout
+	}
+	return defaultGrpcIdleTimeout
+}
+
+// get returns a connection to the broker at address, dialing the connections of the broker on first use.
+// The returned release function must be called once the query completes.
+func (p *grpcConnPool) get(ctx context.Context, address string) (*grpc.ClientConn, func(), error) {
+	p.sweep()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, nil, errGrpcPoolClosed
+	}
+	entry, ok := p.brokers[address]
+	if !ok {
+		dialOptions, err := buildGrpcDialOptions(p.config)
+		if err != nil {
+			return nil, nil, err
+		}
+		entry = &grpcBrokerConns{}
+		for i := 0; i < p.size(); i++ {
+			//nolint:staticcheck // grpc.NewClient lacks context-based timeout semantics here.
+			conn, err := grpcDialContext(ctx, address, dialOptions...)
+			if err != nil {
+				p.closeConns(address, entry.conns)
+				return nil, nil, fmt.Errorf("failed to dial grpc broker %s: %w", address, err)
+			}
+			entry.conns = append(entry.conns, conn)
+		}
+		p.brokers[address] = entry
+	}
+	conn := entry.conns[entry.next%len(entry.conns)]
+	entry.next++
+	entry.inFlight++
+	entry.lastUsed = p.now()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			entry.inFlight--
+			entry.lastUsed = p.now()
+		})
+	}
+	return conn, release, nil
+}
+
+// sweep closes the connections of brokers that have been idle for too long or are no longer
+// listed by the broker selector, at most once per sweep interval. Brokers with queries in flight are kept.
+func (p *grpcConnPool) sweep() {
+	now := p.now()
+	p.mu.Lock()
+	if p.closed || now.Sub(p.lastSweep) < p.sweepInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastSweep = now
+	knownBrokers := p.knownBrokers
+	p.mu.Unlock()
+
+	// The selector is queried without holding the pool lock.
+	var known map[string]bool
+	if knownBrokers != nil {
+		if brokers, err := knownBrokers(); err == nil {
+			known = make(map[string]bool, len(brokers))
+			for _, broker := range brokers {
+				known[normalizeGrpcAddress(broker)] = true
+			}
+		}
+	}
+
+	p.mu.Lock()
+	evicted := make(map[string][]*grpc.ClientConn)
+	for address, entry := range p.brokers {
+		if entry.inFlight > 0 {
+			continue
+		}
+		removed := known != nil && !known[address]
+		if removed || now.Sub(entry.lastUsed) >= p.idleTimeout() {
+			evicted[address] = entry.conns
+			delete(p.brokers, address)
+		}
+	}
+	p.mu.Unlock()
+	for address, conns := range evicted {
+		loggerOrDefault(p.logger).Debug(context.Background(), "Closing idle or removed grpc broker connections", "broker", address)
+		p.closeConns(address, conns)
+	}
+}
+
+// close closes every pooled connection. Connections cannot be obtained from the pool afterwards.
+func (p *grpcConnPool) close() error {
+	p.mu.Lock()
+	p.closed = true
+	brokers := p.brokers
+	p.brokers = make(map[string]*grpcBrokerConns)
+	p.mu.Unlock()
+
+	var errs []error
+	for address, entry := range brokers {
+		for _, conn := range entry.conns {
+			if err := conn.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close grpc connection to %s: %w", address, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *grpcConnPool) closeConns(address string, conns []*grpc.ClientConn) {
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil {
+			loggerOrDefault(p.logger).Error(context.Background(), "Failed to close grpc connection", "broker", address, "error", err)
+		}
+	}
+}
+
+// buildGrpcKeepAliveOptions returns the dial options enabling keepalive pings on pooled connections.
+func buildGrpcKeepAliveOptions(config *GrpcConfig) []grpc.DialOption {
+	var options []grpc.DialOption
+	if config.KeepAliveTime > 0 {
+		timeout := config.KeepAliveTimeout
+		if timeout <= 0 {
+			timeout = defaultGrpcKeepAliveTimeout
+		}
+		options = append(options, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                config.KeepAliveTime,
+			Timeout:             timeout,
+			PermitWithoutStream: config.KeepAlivePermitWithoutStream,
+		}))
+	}
+	return options
+}
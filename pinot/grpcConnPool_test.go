@@ -0,0 +1,147 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	proto "github.com/startreedata/pinot-client-go/pinot/proto"
+)
+
+// countGrpcDials counts the calls to grpcDialContext until the end of the test.
+func countGrpcDials(t *testing.T) *atomic.Int32 {
+	var dials atomic.Int32
+	original := grpcDialContext
+	grpcDialContext = func(ctx context.Context, target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		dials.Add(1)
+		return original(ctx, target, opts...)
+	}
+	t.Cleanup(func() { grpcDialContext = original })
+	return &dials
+}
+
+func TestGrpcTransportReusesConnections(t *testing.T) {
+	dials := countGrpcDials(t)
+	responses := []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[]}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		{
+			Payload:  encodeJSONRowBlock(t, [][]interface{}{{json.Number("1")}}),
+			Metadata: map[string]string{"encoding": "JSON", "compression": "NONE", "rowSize": "1"},
+		},
+	}
+	server, listener, _ := startGrpcTestServer(t, responses)
+	defer server.Stop()
+
+	transport, err := newGrpcBrokerClientTransport(&GrpcConfig{Encoding: "JSON", Compression: "NONE", PoolSize: 2})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		resp, execErr := transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+		require.NoError(t, execErr)
+		assert.Equal(t, 1, resp.ResultTable.GetRowCount())
+	}
+	assert.Equal(t, int32(2), dials.Load())
+
+	require.NoError(t, transport.close())
+	_, err = transport.execute(context.Background(), listener.Addr().String(), &Request{queryFormat: "sql", query: "select 1"})
+	assert.ErrorIs(t, err, errGrpcPoolClosed)
+}
+
+func TestGrpcConnPoolRoundRobin(t *testing.T) {
+	pool := newGrpcConnPool(&GrpcConfig{PoolSize: 3})
+	defer pool.close()
+	seen := map[*grpc.ClientConn]int{}
+	for i := 0; i < 6; i++ {
+		conn, release, err := pool.get(context.Background(), "localhost:1")
+		require.NoError(t, err)
+		seen[conn]++
+		release()
+	}
+	assert.Len(t, seen, 3)
+	for _, count := range seen {
+		assert.Equal(t, 2, count)
+	}
+}
+
+func TestGrpcConnPoolIdleEviction(t *testing.T) {
+	pool := newGrpcConnPool(&GrpcConfig{IdleTimeout: time.Minute})
+	now := time.Unix(0, 0)
+	pool.now = func() time.Time { return now }
+	pool.sweepInterval = 0
+
+	idle, release, err := pool.get(context.Background(), "localhost:1")
+	require.NoError(t, err)
+	release()
+	busy, busyRelease, err := pool.get(context.Background(), "localhost:2")
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, release, err = pool.get(context.Background(), "localhost:3")
+	require.NoError(t, err)
+	release()
+
+	assert.Equal(t, connectivity.Shutdown, idle.GetState())
+	assert.NotEqual(t, connectivity.Shutdown, busy.GetState())
+	assert.NotContains(t, pool.brokers, "localhost:1")
+	assert.Contains(t, pool.brokers, "localhost:2")
+
+	// Once released, the idle connections of the broker are evicted too.
+	busyRelease()
+	busyRelease()
+	now = now.Add(2 * time.Minute)
+	pool.sweep()
+	assert.Equal(t, connectivity.Shutdown, busy.GetState())
+	assert.Empty(t, pool.brokers)
+}
+
+func TestGrpcConnPoolEvictsRemovedBrokers(t *testing.T) {
+	pool := newGrpcConnPool(&GrpcConfig{})
+	defer pool.close()
+	pool.sweepInterval = 0
+	brokers := []string{"localhost:1", "localhost:2"}
+	pool.knownBrokers = func() ([]string, error) { return brokers, nil }
+
+	removed, release, err := pool.get(context.Background(), "localhost:1")
+	require.NoError(t, err)
+	release()
+	kept, release, err := pool.get(context.Background(), "localhost:2")
+	require.NoError(t, err)
+	release()
+
+	brokers = []string{"localhost:2"}
+	pool.sweep()
+	assert.Equal(t, connectivity.Shutdown, removed.GetState())
+	assert.NotEqual(t, connectivity.Shutdown, kept.GetState())
+	assert.NotContains(t, pool.brokers, "localhost:1")
+}
+
+func TestGrpcTransportKnownBrokersFromSelector(t *testing.T) {
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{"localhost:8010"},
+		GrpcConfig: &GrpcConfig{},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	transport, ok := conn.transport.(*grpcBrokerClientTransport)
+	require.True(t, ok)
+	require.NotNil(t, transport.pool.knownBrokers)
+	brokers, err := transport.pool.knownBrokers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:8010"}, brokers)
+}
+
+func TestBuildGrpcKeepAliveOptions(t *testing.T) {
+	assert.Empty(t, buildGrpcKeepAliveOptions(&GrpcConfig{}))
+	assert.Len(t, buildGrpcKeepAliveOptions(&GrpcConfig{KeepAliveTime: time.Minute}), 1)
+
+	options, err := buildGrpcDialOptions(&GrpcConfig{KeepAliveTime: time.Minute, IdleTimeout: time.Minute})
+	require.NoError(t, err)
+	assert.Len(t, options, 3)
+}
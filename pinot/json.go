@@ -3,6 +3,10 @@ package pinot
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 )
 
 // decodeJSONWithNumber use the UseNumber option in std json, which works
@@ -16,3 +20,61 @@ func decodeJSONWithNumber(bodyBytes []byte, out interface{}) error {
 	}
 	return nil
 }
+
+// jsonFieldIndexes caches the JSON field names of struct types, see decodeJSONWithExtra.
+var jsonFieldIndexes sync.Map
+
+// decodeJSONWithExtra decodes a JSON object into out, a pointer to a struct, and returns the fields of the
+// object not matching a field of the struct, nil when there is none. The object is split into its fields
+// once, then each field matching a field of the struct is decoded from its raw value with decodeJSONWithNumber.
+func decodeJSONWithExtra(data []byte, out interface{}) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	target := reflect.ValueOf(out).Elem()
+	known := fieldIndexesOf(target.Type())
+	var extra map[string]json.RawMessage
+	for name, value := range fields {
+		index, ok := known[name]
+		if !ok {
+			// json.Unmarshal matches names case-insensitively.
+			index, ok = known[strings.ToLower(name)]
+		}
+		if !ok {
+			if extra == nil {
+				extra = make(map[string]json.RawMessage)
+			}
+			extra[name] = value
+			continue
+		}
+		if err := decodeJSONWithNumber(value, target.Field(index).Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("failed to decode field %s: %w", name, err)
+		}
+	}
+	return extra, nil
+}
+
+// fieldIndexesOf returns the indexes of the fields of a struct type by JSON name, and by lower-cased JSON name.
+func fieldIndexesOf(structType reflect.Type) map[string]int {
+	if cached, ok := jsonFieldIndexes.Load(structType); ok {
+		if indexes, ok := cached.(map[string]int); ok {
+			return indexes
+		}
+	}
+	indexes := make(map[string]int, 2*structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		indexes[name] = i
+		indexes[strings.ToLower(name)] = i
+	}
+	jsonFieldIndexes.Store(structType, indexes)
+	return indexes
+}
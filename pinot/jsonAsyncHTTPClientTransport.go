@@ -2,14 +2,15 @@ package pinot
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -24,33 +25,14 @@ var jsonMarshal = json.Marshal
 type jsonAsyncHTTPClientTransport struct {
 	client *http.Client
 	header map[string]string
+	logger Logger
 }
 
-func (t jsonAsyncHTTPClientTransport) buildQueryOptions(query *Request) string {
-	queryOptions := ""
-	if query.queryFormat == "sql" {
-		queryOptions = "groupByMode=sql;responseFormat=sql"
-	}
-	if query.useMultistageEngine {
-		if queryOptions != "" {
-			queryOptions += ";"
-		}
-		queryOptions += "useMultistageEngine=true"
-	}
-	if t.client.Timeout > 0 {
-		if queryOptions != "" {
-			queryOptions += ";"
-		}
-		queryOptions += fmt.Sprintf("timeoutMs=%d", t.client.Timeout.Milliseconds())
-	}
-	return queryOptions
-}
-
-func (t jsonAsyncHTTPClientTransport) execute(brokerAddress string, query *Request) (*BrokerResponse, error) {
-	url := fmt.Sprintf(getQueryTemplate(query.queryFormat, brokerAddress), brokerAddress)
+func (t jsonAsyncHTTPClientTransport) execute(ctx context.Context, brokerAddress string, query *Request) (*BrokerResponse, error) {
+	queryURL := fmt.Sprintf(getQueryTemplate(query.queryFormat, brokerAddress), brokerAddress)
 	requestJSON := map[string]string{}
 	requestJSON[query.queryFormat] = query.query
-	queryOptions := t.buildQueryOptions(query)
+	queryOptions := buildQueryOptions(query, queryTimeout(ctx, t.client.Timeout))
 	if queryOptions != "" {
 		requestJSON["queryOptions"] = queryOptions
 	}
@@ -59,20 +41,26 @@ func (t jsonAsyncHTTPClientTransport) execute(brokerAddress string, query *Reque
 	}
 	jsonValue, err := jsonMarshal(requestJSON)
 	if err != nil {
-		log.Error("Unable to marshal request to JSON. ", err)
+		loggerOrDefault(t.logger).Error(ctx, "Unable to marshal request to JSON", "error", err)
 		return nil, err
 	}
-	req, err := createHTTPRequest(url, jsonValue, t.header)
+	req, err := createHTTPRequest(traceRequestSent(ctx), queryURL, jsonValue, t.header)
 	if err != nil {
 		return nil, err
 	}
+	if query.clientQueryID != "" {
+		req.Header.Set("X-Correlation-Id", query.clientQueryID)
+	}
+	for k, v := range query.headers {
+		req.Header.Set(k, v)
+	}
 	resp, err := t.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("got exceptions during sending request. %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Error("Got exceptions during closing response body. ", err)
+			loggerOrDefault(t.logger).Error(ctx, "Got exceptions during closing response body", "error", err)
 		}
 	}()
 	if resp.StatusCode == http.StatusOK {
@@ -86,7 +74,51 @@ func (t jsonAsyncHTTPClientTransport) execute(brokerAddress string, query *Reque
 		}
 		return &brokerResponse, nil
 	}
-	return nil, fmt.Errorf("caught http exception when querying Pinot: %v", resp.Status)
+	return nil, newBrokerHTTPError(brokerAddress, resp)
+}
+
+// newBrokerHTTPError builds the BrokerHTTPError of a non-200 broker response.
+// The body is best effort: a read failure still reports the status code, with the read error appended to the body.
+func newBrokerHTTPError(brokerAddress string, resp *http.Response) *BrokerHTTPError {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPErrorBodySize))
+	if err != nil {
+		return &BrokerHTTPError{Broker: brokerAddress, StatusCode: resp.StatusCode, Body: strings.TrimSpace(fmt.Sprintf("%s (failed to read body: %v)", body, err))}
+	}
+	return &BrokerHTTPError{Broker: brokerAddress, StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// cancel cancels a query running on the broker by its client query ID, through DELETE /query/{id}?client=true.
+func (t jsonAsyncHTTPClientTransport) cancel(ctx context.Context, brokerAddress string, clientQueryID string) error {
+	// The cancellation endpoint is the query endpoint of the broker followed by the query ID.
+	cancelURL := fmt.Sprintf(getQueryTemplate("", brokerAddress), brokerAddress) + "/" + url.PathEscape(clientQueryID) + "?client=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, cancelURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid HTTP request: %w", err)
+	}
+	for k, v := range t.header {
+		req.Header.Add(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("got exceptions during sending cancel request. %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			loggerOrDefault(t.logger).Error(ctx, "Got exceptions during closing response body", "error", err)
+		}
+	}()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s on broker %s", ErrQueryNotFound, clientQueryID, brokerAddress)
+	}
+	return newBrokerHTTPError(brokerAddress, resp)
+}
+
+func (t jsonAsyncHTTPClientTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
 }
 
 func getQueryTemplate(queryFormat string, brokerAddress string) string {
@@ -102,8 +134,8 @@ func getQueryTemplate(queryFormat string, brokerAddress string) string {
 	return "http://%s/query"
 }
 
-func createHTTPRequest(url string, jsonValue []byte, extraHeader map[string]string) (*http.Request, error) {
-	r, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+func createHTTPRequest(ctx context.Context, url string, jsonValue []byte, extraHeader map[string]string) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonValue))
 	if err != nil {
 		return nil, fmt.Errorf("invalid HTTP request: %w", err)
 	}
@@ -1,6 +1,8 @@
 package pinot
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -48,18 +50,18 @@ func TestGetQueryTemplate(t *testing.T) {
 }
 
 func TestCreateHTTPRequest(t *testing.T) {
-	r, err := createHTTPRequest("localhost:8000", []byte(`{"sql": "select * from baseballStats limit 10"}`), map[string]string{"a": "b"})
+	r, err := createHTTPRequest(context.Background(), "localhost:8000", []byte(`{"sql": "select * from baseballStats limit 10"}`), map[string]string{"a": "b"})
 	assert.Nil(t, err)
 	assert.Equal(t, "POST", r.Method)
-	_, err = createHTTPRequest("localhos\t:8000", []byte(`{"sql": "select * from baseballStats limit 10"}`), map[string]string{"a": "b"})
+	_, err = createHTTPRequest(context.Background(), "localhos\t:8000", []byte(`{"sql": "select * from baseballStats limit 10"}`), map[string]string{"a": "b"})
 	assert.NotNil(t, err)
 }
 
 func TestCreateHTTPRequestWithTrace(t *testing.T) {
-	r, err := createHTTPRequest("localhost:8000", []byte(`{"sql": "select * from baseballStats limit 10", "trace": "true"}`), map[string]string{"a": "b"})
+	r, err := createHTTPRequest(context.Background(), "localhost:8000", []byte(`{"sql": "select * from baseballStats limit 10", "trace": "true"}`), map[string]string{"a": "b"})
 	assert.Nil(t, err)
 	assert.Equal(t, "POST", r.Method)
-	_, err = createHTTPRequest("localhos\t:8000", []byte(`{"sql": "select * from baseballStats limit 10", "trace": "true"}`), map[string]string{"a": "b"})
+	_, err = createHTTPRequest(context.Background(), "localhos\t:8000", []byte(`{"sql": "select * from baseballStats limit 10", "trace": "true"}`), map[string]string{"a": "b"})
 	assert.NotNil(t, err)
 }
 
@@ -68,20 +70,20 @@ func TestJsonAsyncHTTPClientTransport(t *testing.T) {
 		client: http.DefaultClient,
 		header: map[string]string{"a": "b"},
 	}
-	_, err := transport.execute("localhos\t:8000", &Request{
+	_, err := transport.execute(context.Background(), "localhos\t:8000", &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 10",
 	})
 	assert.NotNil(t, err)
 	assert.True(t, strings.Contains(err.Error(), "parse "))
 
-	_, err = transport.execute("randomhost", &Request{
+	_, err = transport.execute(context.Background(), "randomhost", &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 10",
 	})
 	assert.NotNil(t, err)
 
-	_, err = transport.execute("localhost:18000", &Request{
+	_, err = transport.execute(context.Background(), "localhost:18000", &Request{
 		queryFormat:         "sql",
 		query:               "select * from baseballStats limit 10",
 		useMultistageEngine: true,
@@ -93,6 +95,7 @@ func TestJsonAsyncHTTPClientTransport(t *testing.T) {
 func TestJsonAsyncHTTPClientTransportNonOKResponse(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("broker failure"))
 	}))
 	defer server.Close()
 
@@ -101,12 +104,17 @@ func TestJsonAsyncHTTPClientTransportNonOKResponse(t *testing.T) {
 		header: map[string]string{},
 	}
 
-	_, err := transport.execute(server.URL, &Request{
+	_, err := transport.execute(context.Background(), server.URL, &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
 	assert.NotNil(t, err)
 	assert.True(t, strings.Contains(err.Error(), "http exception"))
+	var httpErr *BrokerHTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+	assert.Equal(t, "broker failure", httpErr.Body)
+	assert.Equal(t, server.URL, httpErr.Broker)
 }
 
 func TestJsonAsyncHTTPClientTransportTraceAndOptions(t *testing.T) {
@@ -122,7 +130,7 @@ func TestJsonAsyncHTTPClientTransportTraceAndOptions(t *testing.T) {
 		header: map[string]string{"x-test": "1"},
 	}
 
-	_, err := transport.execute(server.URL, &Request{
+	_, err := transport.execute(context.Background(), server.URL, &Request{
 		queryFormat:         "sql",
 		query:               "select * from baseballStats limit 1",
 		useMultistageEngine: true,
@@ -144,7 +152,7 @@ func TestJsonAsyncHTTPClientTransportInvalidJSON(t *testing.T) {
 		header: map[string]string{},
 	}
 
-	_, err := transport.execute(server.URL, &Request{
+	_, err := transport.execute(context.Background(), server.URL, &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -163,7 +171,7 @@ func TestJsonAsyncHTTPClientTransportMarshalError(t *testing.T) {
 		header: map[string]string{},
 	}
 
-	_, err := transport.execute("localhost:8000", &Request{
+	_, err := transport.execute(context.Background(), "localhost:8000", &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -185,7 +193,7 @@ func TestJsonAsyncHTTPClientTransportReadError(t *testing.T) {
 		header: map[string]string{},
 	}
 
-	_, err := transport.execute("http://example.com", &Request{
+	_, err := transport.execute(context.Background(), "http://example.com", &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -209,7 +217,7 @@ func TestJsonAsyncHTTPClientTransportCloseError(t *testing.T) {
 		header: map[string]string{},
 	}
 
-	_, err := transport.execute("http://example.com", &Request{
+	_, err := transport.execute(context.Background(), "http://example.com", &Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 1",
 	})
@@ -223,15 +231,15 @@ func TestBuildQueryOptions(t *testing.T) {
 		},
 		header: map[string]string{"a": "b"},
 	}
-	assert.Equal(t, "groupByMode=sql;responseFormat=sql;timeoutMs=10000", transport.buildQueryOptions(&Request{
+	assert.Equal(t, "groupByMode=sql;responseFormat=sql;timeoutMs=10000", buildQueryOptions(&Request{
 		queryFormat: "sql",
 		query:       "select * from baseballStats limit 10",
-	}))
-	assert.Equal(t, "groupByMode=sql;responseFormat=sql;useMultistageEngine=true;timeoutMs=10000", transport.buildQueryOptions(&Request{
+	}, transport.client.Timeout))
+	assert.Equal(t, "groupByMode=sql;responseFormat=sql;useMultistageEngine=true;timeoutMs=10000", buildQueryOptions(&Request{
 		queryFormat:         "sql",
 		query:               "select * from baseballStats limit 10",
 		useMultistageEngine: true,
-	}))
+	}, transport.client.Timeout))
 
 	transport = &jsonAsyncHTTPClientTransport{
 		client: &http.Client{},
@@ -239,8 +247,8 @@ func TestBuildQueryOptions(t *testing.T) {
 	}
 
 	// should not have timeoutMs
-	assert.Equal(t, "", transport.buildQueryOptions(&Request{
+	assert.Equal(t, "", buildQueryOptions(&Request{
 		queryFormat: "pql",
 		query:       "select * from baseballStats limit 10",
-	}))
+	}, transport.client.Timeout))
 }
@@ -0,0 +1,201 @@
+package pinot
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultEWMADecay = 10 * time.Second
+	// ewmaFailurePenalty is the latency recorded for a failed query when it failed faster,
+	// so that a broker failing fast does not look like the fastest one
+	ewmaFailurePenalty = time.Second
+)
+
+// LoadBalancer picks the broker a query is sent to, among the brokers serving the queried table
+// as discovered by the broker list, Zookeeper or the controller.
+// Implementations must be safe for concurrent use.
+type LoadBalancer interface {
+	// Pick returns one of brokers, which is never empty
+	Pick(brokers []string) string
+	// Begin is called when a query is sent to a broker returned by Pick
+	Begin(broker string)
+	// End is called when the query sent to broker completes, with its latency and transport error
+	End(broker string, latency time.Duration, err error)
+}
+
+// NewRoundRobinLoadBalancer returns a LoadBalancer cycling through the brokers of each query.
+func NewRoundRobinLoadBalancer() LoadBalancer {
+	return &roundRobinLoadBalancer{}
+}
+
+type roundRobinLoadBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobinLoadBalancer) Pick(brokers []string) string {
+	return brokers[(b.next.Add(1)-1)%uint64(len(brokers))]
+}
+
+func (b *roundRobinLoadBalancer) Begin(string) {}
+
+func (b *roundRobinLoadBalancer) End(string, time.Duration, error) {}
+
+// outstandingRequests counts the in-flight queries of each broker.
+type outstandingRequests struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func (o *outstandingRequests) get(broker string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.inFlight[broker]
+}
+
+func (o *outstandingRequests) add(broker string, delta int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.inFlight == nil {
+		o.inFlight = make(map[string]int)
+	}
+	o.inFlight[broker] += delta
+	if o.inFlight[broker] <= 0 {
+		delete(o.inFlight, broker)
+	}
+}
+
+// NewPowerOfTwoChoicesLoadBalancer returns a LoadBalancer picking two brokers at random
+// and sending the query to the one with fewer outstanding queries.
+func NewPowerOfTwoChoicesLoadBalancer() LoadBalancer {
+	return &p2cLoadBalancer{}
+}
+
+type p2cLoadBalancer struct {
+	outstanding outstandingRequests
+}
+
+func (b *p2cLoadBalancer) Pick(brokers []string) string {
+	if len(brokers) == 1 {
+		return brokers[0]
+	}
+	// #nosec G404
+	i := rand.Intn(len(brokers))
+	// #nosec G404
+	j := rand.Intn(len(brokers) - 1)
+	if j >= i {
+		j++
+	}
+	if b.outstanding.get(brokers[j]) < b.outstanding.get(brokers[i]) {
+		return brokers[j]
+	}
+	return brokers[i]
+}
+
+func (b *p2cLoadBalancer) Begin(broker string) {
+	b.outstanding.add(broker, 1)
+}
+
+func (b *p2cLoadBalancer) End(broker string, _ time.Duration, _ error) {
+	b.outstanding.add(broker, -1)
+}
+
+// NewEWMALoadBalancer returns a LoadBalancer sending queries to the broker with the lowest
+// exponentially weighted moving average of latency, weighted by its outstanding queries.
+// Latency peaks are accounted for immediately, while lower latencies decay into the average:
+// decay is the time it takes for past latencies to lose most of their weight - defaults to 10s.
+// Failed queries count as taking at least one second.
+func NewEWMALoadBalancer(decay time.Duration) LoadBalancer {
+	if decay <= 0 {
+		decay = defaultEWMADecay
+	}
+	return &ewmaLoadBalancer{
+		decay:   decay,
+		latency: make(map[string]*ewmaLatency),
+		now:     time.Now,
+	}
+}
+
+type ewmaLatency struct {
+	value      float64
+	lastUpdate time.Time
+}
+
+type ewmaLoadBalancer struct {
+	decay       time.Duration
+	outstanding outstandingRequests
+	mu          sync.Mutex
+	latency     map[string]*ewmaLatency
+	now         func() time.Time
+}
+
+func (b *ewmaLoadBalancer) Pick(brokers []string) string {
+	if len(brokers) == 1 {
+		return brokers[0]
+	}
+	latencies := make([]float64, len(brokers))
+	var known int
+	var sum float64
+	b.mu.Lock()
+	for i, broker := range brokers {
+		latencies[i] = -1
+		if l, ok := b.latency[broker]; ok {
+			latencies[i] = l.value
+			sum += l.value
+			known++
+		}
+	}
+	b.mu.Unlock()
+	// Brokers without history are assumed to be as fast as the average known broker.
+	var unknownLatency float64
+	if known > 0 {
+		unknownLatency = sum / float64(known)
+	}
+
+	// Start from a random broker so that ties are broken randomly.
+	// #nosec G404
+	start := rand.Intn(len(brokers))
+	best, bestCost := "", math.Inf(1)
+	for k := range brokers {
+		i := (start + k) % len(brokers)
+		latency := latencies[i]
+		if latency < 0 {
+			latency = unknownLatency
+		}
+		cost := (latency + 1) * float64(b.outstanding.get(brokers[i])+1)
+		if cost < bestCost {
+			best, bestCost = brokers[i], cost
+		}
+	}
+	return best
+}
+
+func (b *ewmaLoadBalancer) Begin(broker string) {
+	b.outstanding.add(broker, 1)
+}
+
+func (b *ewmaLoadBalancer) End(broker string, latency time.Duration, err error) {
+	b.outstanding.add(broker, -1)
+	if err != nil && latency < ewmaFailurePenalty {
+		latency = ewmaFailurePenalty
+	}
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.latency[broker]
+	if !ok {
+		b.latency[broker] = &ewmaLatency{value: float64(latency), lastUpdate: now}
+		return
+	}
+	if float64(latency) > l.value {
+		// Latency peaks are taken into account at once, so a broker turning slow is avoided quickly.
+		l.value = float64(latency)
+	} else {
+		weight := math.Exp(-float64(now.Sub(l.lastUpdate)) / float64(b.decay))
+		l.value = l.value*weight + float64(latency)*(1-weight)
+	}
+	l.lastUpdate = now
+}
@@ -0,0 +1,171 @@
+package pinot
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundRobinLoadBalancer(t *testing.T) {
+	balancer := NewRoundRobinLoadBalancer()
+	brokers := []string{"b1", "b2", "b3"}
+	var picked []string
+	for i := 0; i < 6; i++ {
+		picked = append(picked, balancer.Pick(brokers))
+	}
+	assert.Equal(t, []string{"b1", "b2", "b3", "b1", "b2", "b3"}, picked)
+	assert.Equal(t, "b1", balancer.Pick([]string{"b1"}))
+}
+
+func TestPowerOfTwoChoicesLoadBalancer(t *testing.T) {
+	balancer := NewPowerOfTwoChoicesLoadBalancer()
+	brokers := []string{"busy", "idle"}
+	for i := 0; i < 5; i++ {
+		balancer.Begin("busy")
+	}
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "idle", balancer.Pick(brokers))
+	}
+	for i := 0; i < 5; i++ {
+		balancer.End("busy", time.Millisecond, nil)
+	}
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		counts[balancer.Pick(brokers)]++
+	}
+	assert.Equal(t, 100, counts["busy"]+counts["idle"])
+	assert.Equal(t, "only", balancer.Pick([]string{"only"}))
+}
+
+func TestEWMALoadBalancerPrefersFastBroker(t *testing.T) {
+	balancer := NewEWMALoadBalancer(time.Second)
+	brokers := []string{"slow", "fast"}
+	balancer.End("slow", 500*time.Millisecond, nil)
+	balancer.End("fast", 5*time.Millisecond, nil)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "fast", balancer.Pick(brokers))
+	}
+
+	// Outstanding queries weigh on the cost of a broker.
+	for i := 0; i < 200; i++ {
+		balancer.Begin("fast")
+	}
+	assert.Equal(t, "slow", balancer.Pick(brokers))
+}
+
+func TestEWMALoadBalancerDecay(t *testing.T) {
+	balancer, ok := NewEWMALoadBalancer(time.Second).(*ewmaLoadBalancer)
+	require.True(t, ok)
+	now := time.Unix(0, 0)
+	balancer.now = func() time.Time { return now }
+
+	balancer.End("b1", 100*time.Millisecond, nil)
+	// A peak is taken into account at once.
+	balancer.End("b1", 300*time.Millisecond, nil)
+	assert.Equal(t, float64(300*time.Millisecond), balancer.latency["b1"].value)
+
+	// Lower latencies decay into the average.
+	now = now.Add(time.Second)
+	balancer.End("b1", 100*time.Millisecond, nil)
+	value := balancer.latency["b1"].value
+	assert.Less(t, value, float64(300*time.Millisecond))
+	assert.Greater(t, value, float64(100*time.Millisecond))
+
+	// Failures count as slow queries.
+	balancer.End("b2", time.Millisecond, errors.New("connection refused"))
+	assert.Equal(t, float64(ewmaFailurePenalty), balancer.latency["b2"].value)
+}
+
+func TestEWMALoadBalancerUnknownBrokers(t *testing.T) {
+	balancer := NewEWMALoadBalancer(0)
+	balancer.End("known", 10*time.Millisecond, nil)
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		counts[balancer.Pick([]string{"known", "new"})]++
+	}
+	// Brokers without history are assumed as fast as the known ones, so both get picked.
+	assert.Greater(t, counts["known"], 0)
+	assert.Greater(t, counts["new"], 0)
+}
+
+type recordingLoadBalancer struct {
+	picked   []string
+	begins   int
+	ends     int
+	failures int
+}
+
+func (b *recordingLoadBalancer) Pick(brokers []string) string {
+	b.picked = append(b.picked, brokers...)
+	return brokers[len(brokers)-1]
+}
+
+func (b *recordingLoadBalancer) Begin(string) {
+	b.begins++
+}
+
+func (b *recordingLoadBalancer) End(_ string, _ time.Duration, err error) {
+	b.ends++
+	if err != nil {
+		b.failures++
+	}
+}
+
+func TestConnectionUsesLoadBalancer(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	balancer := &recordingLoadBalancer{}
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:   []string{"localhost:1", ts.URL},
+		LoadBalancer: balancer,
+	})
+	require.NoError(t, err)
+
+	_, err = conn.ExecuteSQL("", "select 1")
+	require.NoError(t, err)
+	_, err = conn.With(WithTrace()).ExecuteSQL("", "select 1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:1", ts.URL, "localhost:1", ts.URL}, balancer.picked)
+	assert.Equal(t, 2, balancer.begins)
+	assert.Equal(t, 2, balancer.ends)
+	assert.Equal(t, 0, balancer.failures)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLoadBalancerWithTableAwareSelector(t *testing.T) {
+	balancer := NewRoundRobinLoadBalancer()
+	transport := &mockTransport{}
+	conn := &Connection{
+		transport: transport,
+		brokerSelector: &controllerBasedSelector{tableAwareBrokerSelector: tableAwareBrokerSelector{
+			tableBrokerMap: map[string][]string{"myTable": {"b1:8000", "b2:8000"}},
+		}},
+		loadBalancer: balancer,
+	}
+	// Requests carry a random client query ID.
+	isSelectOne := mock.MatchedBy(func(r *Request) bool { return r.queryFormat == "sql" && r.query == "select 1" })
+	transport.On("execute", "b1:8000", isSelectOne).Return(&BrokerResponse{}, nil).Once()
+	transport.On("execute", "b2:8000", isSelectOne).Return(&BrokerResponse{}, nil).Once()
+	for i := 0; i < 2; i++ {
+		_, err := conn.ExecuteSQL("myTable", "select 1")
+		require.NoError(t, err)
+	}
+	transport.AssertExpectations(t)
+
+	_, err := conn.ExecuteSQL("unknownTable", "select 1")
+	assert.ErrorIs(t, err, ErrTableNotFound)
+}
@@ -0,0 +1,92 @@
+package pinot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger receives the log messages of the client, see ClientConfig.Logger. The context is the one of the
+// query being logged, when there is one. keyvals alternate keys and values, as with slog.
+type Logger interface {
+	Debug(ctx context.Context, msg string, keyvals ...any)
+	Info(ctx context.Context, msg string, keyvals ...any)
+	Warn(ctx context.Context, msg string, keyvals ...any)
+	Error(ctx context.Context, msg string, keyvals ...any)
+}
+
+// defaultLogger logs through the standard logrus logger, as in previous versions of the client.
+var defaultLogger = NewLogrusLogger(logrus.StandardLogger())
+
+// loggerOrDefault returns logger, or the default logger when it is nil.
+func loggerOrDefault(logger Logger) Logger {
+	if logger == nil {
+		return defaultLogger
+	}
+	return logger
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to a slog handler. Use slog.DiscardHandler to silence the client.
+func NewSlogLogger(handler slog.Handler) Logger {
+	return slogLogger{logger: slog.New(handler)}
+}
+
+func (l slogLogger) Debug(ctx context.Context, msg string, keyvals ...any) {
+	l.logger.Log(ctx, slog.LevelDebug, msg, keyvals...)
+}
+
+func (l slogLogger) Info(ctx context.Context, msg string, keyvals ...any) {
+	l.logger.Log(ctx, slog.LevelInfo, msg, keyvals...)
+}
+
+func (l slogLogger) Warn(ctx context.Context, msg string, keyvals ...any) {
+	l.logger.Log(ctx, slog.LevelWarn, msg, keyvals...)
+}
+
+func (l slogLogger) Error(ctx context.Context, msg string, keyvals ...any) {
+	l.logger.Log(ctx, slog.LevelError, msg, keyvals...)
+}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// NewLogrusLogger returns a Logger writing to a logrus logger or entry, keyvals becoming fields.
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+func (l logrusLogger) Debug(ctx context.Context, msg string, keyvals ...any) {
+	l.log(ctx, logrus.DebugLevel, msg, keyvals)
+}
+
+func (l logrusLogger) Info(ctx context.Context, msg string, keyvals ...any) {
+	l.log(ctx, logrus.InfoLevel, msg, keyvals)
+}
+
+func (l logrusLogger) Warn(ctx context.Context, msg string, keyvals ...any) {
+	l.log(ctx, logrus.WarnLevel, msg, keyvals)
+}
+
+func (l logrusLogger) Error(ctx context.Context, msg string, keyvals ...any) {
+	l.log(ctx, logrus.ErrorLevel, msg, keyvals)
+}
+
+func (l logrusLogger) log(ctx context.Context, level logrus.Level, msg string, keyvals []any) {
+	fields := make(logrus.Fields, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			// Same key as slog for a value without key.
+			fields["!BADKEY"] = keyvals[i]
+			break
+		}
+		fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+	l.logger.WithFields(fields).WithContext(ctx).Log(level, msg)
+}
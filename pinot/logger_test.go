@@ -0,0 +1,134 @@
+package pinot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type requestIDKey struct{}
+
+// contextHandler adds the request ID of the context to the records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		record.AddAttrs(slog.String("requestId", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &fields))
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(contextHandler{slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})})
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r1")
+	logger.Debug(ctx, "hidden")
+	logger.Info(ctx, "info", "broker", "b1:8000")
+	logger.Warn(ctx, "warn", "attempt", 2)
+	logger.Error(context.Background(), "error", "error", errors.New("boom"))
+
+	lines := decodeLogLines(t, &buf)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "b1:8000", lines[0]["broker"])
+	assert.Equal(t, "r1", lines[0]["requestId"])
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, float64(2), lines[1]["attempt"])
+	assert.Equal(t, "ERROR", lines[2]["level"])
+	assert.Equal(t, "boom", lines[2]["error"])
+	assert.NotContains(t, lines[2], "requestId")
+}
+
+func TestLogrusLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := logrus.New()
+	base.SetOutput(&buf)
+	base.SetFormatter(&logrus.JSONFormatter{})
+	base.SetLevel(logrus.InfoLevel)
+	logger := NewLogrusLogger(base.WithField("component", "pinot"))
+
+	logger.Debug(context.Background(), "hidden")
+	logger.Warn(context.Background(), "warn", "broker", "b1:8000", "dangling")
+	logger.Error(context.Background(), "error", "error", errors.New("boom"))
+
+	lines := decodeLogLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "warning", lines[0]["level"])
+	assert.Equal(t, "warn", lines[0]["msg"])
+	assert.Equal(t, "pinot", lines[0]["component"])
+	assert.Equal(t, "b1:8000", lines[0]["broker"])
+	assert.Equal(t, "dangling", lines[0]["!BADKEY"])
+	assert.Equal(t, "boom", lines[1]["error"])
+}
+
+func TestConnectionLogger(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["INT"],"columnNames":["n"]},"rows":[["abc"]]},"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{ts.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 2},
+		Logger:      NewSlogLogger(contextHandler{slog.NewJSONHandler(&buf, nil)}),
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r2")
+	resp, err := conn.With(WithTrace()).ExecuteSQLContext(ctx, "t", "select n from t")
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), resp.ResultTable.GetInt(0, 0))
+
+	lines := decodeLogLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "Query attempt failed, retrying", lines[0]["msg"])
+	assert.Equal(t, ts.URL, lines[0]["broker"])
+	assert.Equal(t, "r2", lines[0]["requestId"])
+	assert.Equal(t, "Error converting to int", lines[1]["msg"])
+
+	// A silenced connection does not log through the standard logrus logger either.
+	buf.Reset()
+	standard := logrus.StandardLogger()
+	output := standard.Out
+	standard.SetOutput(&buf)
+	defer standard.SetOutput(output)
+	silent, err := NewWithConfig(&ClientConfig{BrokerList: []string{ts.URL}, Logger: NewSlogLogger(slog.DiscardHandler)})
+	require.NoError(t, err)
+	resp, err = silent.ExecuteSQL("t", "select n from t")
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), resp.ResultTable.GetInt(0, 0))
+	assert.Empty(t, buf.String())
+}
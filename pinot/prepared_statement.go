@@ -1,6 +1,7 @@
 package pinot
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -47,6 +48,21 @@ type PreparedStatement interface {
 	Close() error
 }
 
+// PreparedStatementContext is a PreparedStatement whose executions take a context. The statements
+// returned by Connection.Prepare implement it. It is a separate interface so that existing
+// implementations of PreparedStatement remain valid.
+type PreparedStatementContext interface {
+	PreparedStatement
+
+	// ExecuteContext executes the prepared statement with the currently set parameters,
+	// aborting the query when ctx is cancelled or its deadline is reached
+	ExecuteContext(ctx context.Context) (*BrokerResponse, error)
+
+	// ExecuteWithParamsContext executes the prepared statement with the given parameters,
+	// aborting the query when ctx is cancelled or its deadline is reached
+	ExecuteWithParamsContext(ctx context.Context, params ...interface{}) (*BrokerResponse, error)
+}
+
 // preparedStatement is the concrete implementation of PreparedStatement
 type preparedStatement struct {
 	connection    *Connection
@@ -59,7 +75,10 @@ type preparedStatement struct {
 	closed        bool
 }
 
+var _ PreparedStatementContext = (*preparedStatement)(nil)
+
 // Prepare creates a new PreparedStatement for the given table and query template.
+// The returned statement also implements PreparedStatementContext.
 // The query template should use '?' as placeholders for parameters.
 // Example: "SELECT * FROM table WHERE column1 = ? AND column2 = ?"
 func (c *Connection) Prepare(table string, queryTemplate string) (PreparedStatement, error) {
@@ -134,6 +153,11 @@ func (ps *preparedStatement) Set(parameterIndex int, value interface{}) error {
 
 // Execute executes the prepared statement with the currently set parameters
 func (ps *preparedStatement) Execute() (*BrokerResponse, error) {
+	return ps.ExecuteContext(context.Background())
+}
+
+// ExecuteContext executes the prepared statement with the currently set parameters, honoring ctx
+func (ps *preparedStatement) ExecuteContext(ctx context.Context) (*BrokerResponse, error) {
 	ps.mutex.RLock()
 	defer ps.mutex.RUnlock()
 
@@ -155,12 +179,17 @@ func (ps *preparedStatement) Execute() (*BrokerResponse, error) {
 	}
 
 	// Execute the query using the connection
-	return ps.connection.ExecuteSQL(ps.table, query)
+	return ps.connection.ExecuteSQLContext(ctx, ps.table, query)
 }
 
 // ExecuteWithParams executes the prepared statement with the given parameters
 // This is a convenience method that sets all parameters and executes in one call
 func (ps *preparedStatement) ExecuteWithParams(params ...interface{}) (*BrokerResponse, error) {
+	return ps.ExecuteWithParamsContext(context.Background(), params...)
+}
+
+// ExecuteWithParamsContext executes the prepared statement with the given parameters, honoring ctx
+func (ps *preparedStatement) ExecuteWithParamsContext(ctx context.Context, params ...interface{}) (*BrokerResponse, error) {
 	ps.mutex.Lock()
 
 	if ps.closed {
@@ -185,7 +214,7 @@ func (ps *preparedStatement) ExecuteWithParams(params ...interface{}) (*BrokerRe
 	ps.mutex.Unlock()
 
 	// Execute the query using the connection (without holding the lock)
-	return ps.connection.ExecuteSQL(table, query)
+	return ps.connection.ExecuteSQLContext(ctx, table, query)
 }
 
 // GetQuery returns the original query template
@@ -1,6 +1,7 @@
 package pinot
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -367,3 +368,30 @@ func TestPreparedStatement_ConcurrentUsage(t *testing.T) {
 	err = stmt.Close()
 	assert.NoError(t, err)
 }
+
+func TestPreparedStatement_ExecuteContext_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	pinotClient, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+	prepared, err := pinotClient.Prepare("testTable", "SELECT * FROM testTable WHERE id = ?")
+	require.NoError(t, err)
+	stmt, ok := prepared.(PreparedStatementContext)
+	require.True(t, ok)
+	require.NoError(t, stmt.SetInt(1, 123))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = stmt.ExecuteContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = stmt.ExecuteWithParamsContext(ctx, 456)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,159 @@
+package pinot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryOptions holds Pinot query options sent to the broker along with a query.
+// Zero values are not sent, so the broker side defaults apply.
+// Connection level defaults from ClientConfig.QueryOptions are merged with per-query options,
+// with non-zero per-query values taking precedence.
+type QueryOptions struct {
+	// MaxExecutionThreads limits the number of threads used by each server to execute the query
+	MaxExecutionThreads int
+	// EnableNullHandling enables null value support in query execution
+	EnableNullHandling bool
+	// NumReplicaGroupsToQuery is the number of replica groups the broker routes the query to
+	NumReplicaGroupsToQuery int
+	// MinSegmentGroupTrimSize is the minimum number of groups kept when trimming group-by results per segment
+	MinSegmentGroupTrimSize int
+	// MaxServerResponseSizeBytes caps the size of the response returned by each server
+	MaxServerResponseSizeBytes int64
+	// SkipUpsert queries every record of an upsert table instead of only the latest ones
+	SkipUpsert bool
+	// ClientQueryID identifies the query on the broker, to cancel it with Connection.Cancel.
+	// It is meant to be set per query; a random ID is generated when it is empty.
+	ClientQueryID string
+	// Extra holds arbitrary query options by name. Extra entries take precedence over the typed fields
+	// and over options set by the client, such as timeoutMs.
+	Extra map[string]string
+}
+
+// merge returns the options of o overridden by the non-zero values of override.
+// It returns nil when both are nil, and never modifies its inputs.
+func (o *QueryOptions) merge(override *QueryOptions) *QueryOptions {
+	if o == nil && override == nil {
+		return nil
+	}
+	merged := &QueryOptions{}
+	for _, options := range []*QueryOptions{o, override} {
+		if options == nil {
+			continue
+		}
+		if options.MaxExecutionThreads != 0 {
+			merged.MaxExecutionThreads = options.MaxExecutionThreads
+		}
+		if options.EnableNullHandling {
+			merged.EnableNullHandling = true
+		}
+		if options.NumReplicaGroupsToQuery != 0 {
+			merged.NumReplicaGroupsToQuery = options.NumReplicaGroupsToQuery
+		}
+		if options.MinSegmentGroupTrimSize != 0 {
+			merged.MinSegmentGroupTrimSize = options.MinSegmentGroupTrimSize
+		}
+		if options.MaxServerResponseSizeBytes != 0 {
+			merged.MaxServerResponseSizeBytes = options.MaxServerResponseSizeBytes
+		}
+		if options.SkipUpsert {
+			merged.SkipUpsert = true
+		}
+		if options.ClientQueryID != "" {
+			merged.ClientQueryID = options.ClientQueryID
+		}
+		for k, v := range options.Extra {
+			if merged.Extra == nil {
+				merged.Extra = make(map[string]string, len(options.Extra))
+			}
+			merged.Extra[k] = v
+		}
+	}
+	return merged
+}
+
+// clientQueryIDOption is the query option identifying a query on the broker.
+const clientQueryIDOption = "clientQueryId"
+
+// queryOption is a single key=value entry of the queryOptions string.
+type queryOption struct {
+	key   string
+	value string
+}
+
+// buildQueryOptions serializes the query options of a request into the semicolon separated
+// key=value format understood by the broker. Both the JSON and gRPC transports use it,
+// so a query is sent with the same options whichever transport is configured.
+func buildQueryOptions(query *Request, timeout time.Duration) string {
+	var options []queryOption
+	if query.queryFormat == "sql" {
+		options = append(options, queryOption{"groupByMode", "sql"}, queryOption{"responseFormat", "sql"})
+	}
+	if query.useMultistageEngine {
+		options = append(options, queryOption{"useMultistageEngine", "true"})
+	}
+	if timeout > 0 {
+		options = append(options, queryOption{"timeoutMs", strconv.FormatInt(timeout.Milliseconds(), 10)})
+	}
+	if opts := query.queryOptions; opts != nil {
+		if opts.MaxExecutionThreads != 0 {
+			options = append(options, queryOption{"maxExecutionThreads", strconv.Itoa(opts.MaxExecutionThreads)})
+		}
+		if opts.EnableNullHandling {
+			options = append(options, queryOption{"enableNullHandling", "true"})
+		}
+		if opts.NumReplicaGroupsToQuery != 0 {
+			options = append(options, queryOption{"numReplicaGroupsToQuery", strconv.Itoa(opts.NumReplicaGroupsToQuery)})
+		}
+		if opts.MinSegmentGroupTrimSize != 0 {
+			options = append(options, queryOption{"minSegmentGroupTrimSize", strconv.Itoa(opts.MinSegmentGroupTrimSize)})
+		}
+		if opts.MaxServerResponseSizeBytes != 0 {
+			options = append(options, queryOption{"maxServerResponseSizeBytes", strconv.FormatInt(opts.MaxServerResponseSizeBytes, 10)})
+		}
+		if opts.SkipUpsert {
+			options = append(options, queryOption{"skipUpsert", "true"})
+		}
+	}
+	if query.clientQueryID != "" {
+		options = append(options, queryOption{clientQueryIDOption, query.clientQueryID})
+	}
+	if query.queryOptions != nil {
+		options = applyExtraQueryOptions(options, query.queryOptions.Extra)
+	}
+
+	entries := make([]string, 0, len(options))
+	for _, option := range options {
+		entries = append(entries, fmt.Sprintf("%s=%s", option.key, option.value))
+	}
+	return strings.Join(entries, ";")
+}
+
+// applyExtraQueryOptions overrides existing options with the extra ones in place and appends
+// the remaining extra options sorted by key, to keep the serialized string deterministic.
+func applyExtraQueryOptions(options []queryOption, extra map[string]string) []queryOption {
+	if len(extra) == 0 {
+		return options
+	}
+	applied := make(map[string]bool, len(extra))
+	for i := range options {
+		if value, ok := extra[options[i].key]; ok {
+			options[i].value = value
+			applied[options[i].key] = true
+		}
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		if !applied[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		options = append(options, queryOption{k, extra[k]})
+	}
+	return options
+}
@@ -0,0 +1,118 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryOptionsMerge(t *testing.T) {
+	var nilOptions *QueryOptions
+	assert.Nil(t, nilOptions.merge(nil))
+
+	defaults := &QueryOptions{
+		MaxExecutionThreads: 4,
+		SkipUpsert:          true,
+		Extra:               map[string]string{"a": "1", "b": "2"},
+	}
+	merged := defaults.merge(&QueryOptions{
+		MaxExecutionThreads:     8,
+		NumReplicaGroupsToQuery: 2,
+		Extra:                   map[string]string{"b": "3"},
+	})
+	assert.Equal(t, &QueryOptions{
+		MaxExecutionThreads:     8,
+		NumReplicaGroupsToQuery: 2,
+		SkipUpsert:              true,
+		Extra:                   map[string]string{"a": "1", "b": "3"},
+	}, merged)
+	// Inputs are left untouched.
+	assert.Equal(t, 4, defaults.MaxExecutionThreads)
+	assert.Equal(t, "2", defaults.Extra["b"])
+
+	assert.Equal(t, &QueryOptions{EnableNullHandling: true}, nilOptions.merge(&QueryOptions{EnableNullHandling: true}))
+	assert.Equal(t, &QueryOptions{MaxServerResponseSizeBytes: 10}, (&QueryOptions{MaxServerResponseSizeBytes: 10}).merge(nil))
+}
+
+func TestBuildQueryOptionsTypedFields(t *testing.T) {
+	options := buildQueryOptions(&Request{
+		queryFormat:         "sql",
+		useMultistageEngine: true,
+		queryOptions: &QueryOptions{
+			MaxExecutionThreads:        2,
+			EnableNullHandling:         true,
+			NumReplicaGroupsToQuery:    1,
+			MinSegmentGroupTrimSize:    100,
+			MaxServerResponseSizeBytes: 1048576,
+			SkipUpsert:                 true,
+		},
+	}, 5*time.Second)
+	assert.Equal(t, "groupByMode=sql;responseFormat=sql;useMultistageEngine=true;timeoutMs=5000;"+
+		"maxExecutionThreads=2;enableNullHandling=true;numReplicaGroupsToQuery=1;minSegmentGroupTrimSize=100;"+
+		"maxServerResponseSizeBytes=1048576;skipUpsert=true", options)
+}
+
+func TestBuildQueryOptionsExtra(t *testing.T) {
+	options := buildQueryOptions(&Request{
+		queryFormat: "sql",
+		queryOptions: &QueryOptions{
+			MaxExecutionThreads: 2,
+			Extra: map[string]string{
+				"timeoutMs":           "100",
+				"maxExecutionThreads": "3",
+				"useStarTree":         "false",
+				"explainPlanVerbose":  "true",
+			},
+		},
+	}, 5*time.Second)
+	assert.Equal(t, "groupByMode=sql;responseFormat=sql;timeoutMs=100;maxExecutionThreads=3;explainPlanVerbose=true;useStarTree=false", options)
+}
+
+func TestQueryOptionsSentThroughConnection(t *testing.T) {
+	var queryOptions string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		queryOptions = request["queryOptions"]
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{ts.URL},
+		QueryOptions: &QueryOptions{
+			MaxExecutionThreads: 4,
+			SkipUpsert:          true,
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = conn.ExecuteSQL("baseballStats", "select 1")
+	require.NoError(t, err)
+	assert.Regexp(t, "^groupByMode=sql;responseFormat=sql;maxExecutionThreads=4;skipUpsert=true;clientQueryId=[0-9a-f-]{36}$", queryOptions)
+
+	_, err = conn.ExecuteSQLWithOptions(context.Background(), "baseballStats", "select 1", &QueryOptions{
+		MaxExecutionThreads: 1,
+		EnableNullHandling:  true,
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, "^groupByMode=sql;responseFormat=sql;maxExecutionThreads=1;enableNullHandling=true;skipUpsert=true;clientQueryId=[0-9a-f-]{36}$", queryOptions)
+}
+
+func TestQueryOptionsSameForJSONAndGrpc(t *testing.T) {
+	request := &Request{
+		queryFormat:  "sql",
+		queryOptions: &QueryOptions{NumReplicaGroupsToQuery: 2, Extra: map[string]string{"useStarTree": "false"}},
+	}
+	metadata := buildGrpcMetadata(&GrpcConfig{}, request, time.Second)
+	assert.Equal(t, buildQueryOptions(request, time.Second), metadata["queryOptions"])
+	assert.Equal(t, "groupByMode=sql;responseFormat=sql;timeoutMs=1000;numReplicaGroupsToQuery=2;useStarTree=false", metadata["queryOptions"])
+}
@@ -0,0 +1,182 @@
+package pinot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QueryPlan is the operator tree of a query, as returned by EXPLAIN PLAN FOR queries.
+// Single-stage engine plans name operators such as BROKER_REDUCE or FILTER_SORTED_INDEX,
+// multi-stage engine plans name Calcite relational operators such as LogicalProject or
+// PinotLogicalExchange.
+type QueryPlan struct {
+	Root *PlanNode
+}
+
+// PlanNode is an operator of a QueryPlan.
+type PlanNode struct {
+	// Operator is the name of the operator, without its attributes.
+	Operator string
+	// Attributes holds the attributes of the operator as printed by Pinot, such as "limit:10"
+	// or "offset=[0], fetch=[10]", empty when the operator has none.
+	Attributes string
+	Children   []*PlanNode
+}
+
+// Explain returns the plan of an SQL query for a given table, executing it as EXPLAIN PLAN FOR query.
+// The plan is the one of the engine the query would run on, see WithMultistage.
+// Pinot exceptions in the response are returned as errors.
+func (c *Connection) Explain(ctx context.Context, table string, query string) (*QueryPlan, error) {
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "EXPLAIN") {
+		query = "EXPLAIN PLAN FOR " + query
+	}
+	resp, err := c.ExecuteSQLContext(ctx, table, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+	if resp.ResultTable == nil {
+		return nil, fmt.Errorf("explain plan response has no result table")
+	}
+	return ParseQueryPlan(resp.ResultTable)
+}
+
+// ParseQueryPlan parses the result of an EXPLAIN PLAN FOR query. Single-stage engine results have one
+// row per operator, with Operator, Operator_Id and Parent_Id columns, while multi-stage engine results
+// have the plan as indented text in a PLAN column.
+func ParseQueryPlan(table *ResultTable) (*QueryPlan, error) {
+	if planColumn, err := table.ColumnIndex("PLAN"); err == nil {
+		if table.GetRowCount() == 0 {
+			return nil, fmt.Errorf("explain plan result has no row")
+		}
+		return parseMultistagePlan(table.GetString(0, planColumn))
+	}
+	return parseOperatorRows(table)
+}
+
+// parseOperatorRows parses a single-stage engine plan. PLAN_START rows, with an operator id of -1,
+// introduce the operators run on some of the segments: they become the parent of the following
+// operators attached to the same parent.
+func parseOperatorRows(table *ResultTable) (*QueryPlan, error) {
+	var columns [3]int
+	for i, name := range []string{"Operator", "Operator_Id", "Parent_Id"} {
+		index, err := table.ColumnIndex(name)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported explain plan result with columns %v", table.DataSchema.ColumnNames)
+		}
+		columns[i] = index
+	}
+	plan := &QueryPlan{}
+	nodes := make(map[int64]*PlanNode)
+	planStarts := make(map[int64]*PlanNode)
+	var planStart *PlanNode
+	for row := 0; row < table.GetRowCount(); row++ {
+		node := newPlanNode(table.GetString(row, columns[0]))
+		id, err := table.GetLongE(row, columns[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid operator id: %w", err)
+		}
+		parentID, err := table.GetLongE(row, columns[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid parent id: %w", err)
+		}
+		if id < 0 {
+			planStart = node
+			continue
+		}
+		nodes[id] = node
+		parent, ok := nodes[parentID]
+		if !ok {
+			if plan.Root != nil {
+				return nil, fmt.Errorf("operator %d has unknown parent %d", id, parentID)
+			}
+			plan.Root = node
+			continue
+		}
+		if planStart != nil {
+			parent.Children = append(parent.Children, planStart)
+			planStarts[parentID] = planStart
+			planStart = nil
+		}
+		if start, found := planStarts[parentID]; found {
+			parent = start
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	if plan.Root == nil {
+		return nil, fmt.Errorf("explain plan result has no operator")
+	}
+	return plan, nil
+}
+
+// parseMultistagePlan parses a multi-stage engine plan, one operator per line indented by two spaces
+// per level, after an "Execution Plan" title line.
+func parseMultistagePlan(text string) (*QueryPlan, error) {
+	plan := &QueryPlan{}
+	var stack []*PlanNode
+	for _, line := range strings.Split(text, "\n") {
+		operator := strings.TrimLeft(line, " ")
+		if operator == "" || operator == "Execution Plan" {
+			continue
+		}
+		depth := (len(line) - len(operator)) / 2
+		node := newPlanNode(strings.TrimRight(operator, " \r"))
+		if depth > len(stack) {
+			return nil, fmt.Errorf("invalid indentation of plan line %q", line)
+		}
+		stack = stack[:depth]
+		if depth == 0 {
+			if plan.Root != nil {
+				return nil, fmt.Errorf("plan has several root operators")
+			}
+			plan.Root = node
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	if plan.Root == nil {
+		return nil, fmt.Errorf("explain plan result has no operator")
+	}
+	return plan, nil
+}
+
+// newPlanNode splits an operator as printed by Pinot, such as "BROKER_REDUCE(limit:10)", into its name
+// and attributes.
+func newPlanNode(operator string) *PlanNode {
+	name, attributes, ok := strings.Cut(operator, "(")
+	if !ok {
+		return &PlanNode{Operator: operator}
+	}
+	return &PlanNode{Operator: name, Attributes: strings.TrimSuffix(attributes, ")")}
+}
+
+// String renders the plan as an indented operator tree, one operator per line.
+func (p *QueryPlan) String() string {
+	if p == nil || p.Root == nil {
+		return ""
+	}
+	var b strings.Builder
+	writeTree(&b, p.Root, 0, func(n *PlanNode) (string, []*PlanNode) {
+		if n.Attributes == "" {
+			return n.Operator, n.Children
+		}
+		return n.Operator + "(" + n.Attributes + ")", n.Children
+	})
+	return b.String()
+}
+
+// writeTree writes a line per node of a tree, children being indented by two spaces.
+func writeTree[N any](b *strings.Builder, node N, depth int, describe func(N) (string, []N)) {
+	line, children := describe(node)
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString(line)
+	b.WriteByte('\n')
+	for _, child := range children {
+		writeTree(b, child, depth+1, describe)
+	}
+}
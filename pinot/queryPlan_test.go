@@ -0,0 +1,131 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func operatorRowsTable(rows ...[]interface{}) *ResultTable {
+	table := &ResultTable{
+		DataSchema: RespSchema{
+			ColumnNames:     []string{"Operator", "Operator_Id", "Parent_Id"},
+			ColumnDataTypes: []string{"STRING", "INT", "INT"},
+		},
+		Rows: rows,
+	}
+	table.indexColumns()
+	return table
+}
+
+func TestParseQueryPlanSingleStage(t *testing.T) {
+	plan, err := ParseQueryPlan(operatorRowsTable(
+		[]interface{}{"BROKER_REDUCE(limit:10)", json.Number("1"), json.Number("0")},
+		[]interface{}{"COMBINE_SELECT", json.Number("2"), json.Number("1")},
+		[]interface{}{"PLAN_START(numSegmentsForThisPlan:1)", json.Number("-1"), json.Number("-1")},
+		[]interface{}{"SELECT(selectList:playerName)", json.Number("3"), json.Number("2")},
+		[]interface{}{"PROJECT(playerName)", json.Number("4"), json.Number("3")},
+		[]interface{}{"FILTER_MATCH_ENTIRE_SEGMENT(docs:97889)", json.Number("5"), json.Number("4")},
+		[]interface{}{"PLAN_START(numSegmentsForThisPlan:2)", json.Number("-1"), json.Number("-1")},
+		[]interface{}{"SELECT(selectList:playerName)", json.Number("6"), json.Number("2")},
+		[]interface{}{"FILTER_EMPTY", json.Number("7"), json.Number("6")},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, "BROKER_REDUCE", plan.Root.Operator)
+	assert.Equal(t, "limit:10", plan.Root.Attributes)
+	combine := plan.Root.Children[0]
+	assert.Equal(t, "COMBINE_SELECT", combine.Operator)
+	assert.Equal(t, "", combine.Attributes)
+	require.Len(t, combine.Children, 2)
+	assert.Equal(t, "numSegmentsForThisPlan:2", combine.Children[1].Attributes)
+	assert.Equal(t, "FILTER_EMPTY", combine.Children[1].Children[0].Children[0].Operator)
+	assert.Equal(t, `BROKER_REDUCE(limit:10)
+  COMBINE_SELECT
+    PLAN_START(numSegmentsForThisPlan:1)
+      SELECT(selectList:playerName)
+        PROJECT(playerName)
+          FILTER_MATCH_ENTIRE_SEGMENT(docs:97889)
+    PLAN_START(numSegmentsForThisPlan:2)
+      SELECT(selectList:playerName)
+        FILTER_EMPTY
+`, plan.String())
+}
+
+func TestParseQueryPlanMultistage(t *testing.T) {
+	table := &ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"SQL", "PLAN"}, ColumnDataTypes: []string{"STRING", "STRING"}},
+		Rows: [][]interface{}{{"select ...", `Execution Plan
+LogicalSort(offset=[0], fetch=[10])
+  PinotLogicalSortExchange(distribution=[hash], collation=[[]])
+    LogicalJoin(condition=[=($0, $1)], joinType=[inner])
+      PinotLogicalExchange(distribution=[hash[0]])
+        LogicalTableScan(table=[[default, a]])
+      PinotLogicalExchange(distribution=[hash[0]])
+        LogicalTableScan(table=[[default, b]])
+`}},
+	}
+	table.indexColumns()
+	plan, err := ParseQueryPlan(table)
+	require.NoError(t, err)
+	assert.Equal(t, "LogicalSort", plan.Root.Operator)
+	assert.Equal(t, "offset=[0], fetch=[10]", plan.Root.Attributes)
+	join := plan.Root.Children[0].Children[0]
+	assert.Equal(t, "LogicalJoin", join.Operator)
+	require.Len(t, join.Children, 2)
+	assert.Equal(t, "table=[[default, b]]", join.Children[1].Children[0].Attributes)
+	assert.Equal(t, table.GetString(0, 1)[len("Execution Plan\n"):], plan.String())
+}
+
+func TestParseQueryPlanErrors(t *testing.T) {
+	_, err := ParseQueryPlan(&ResultTable{DataSchema: RespSchema{ColumnNames: []string{"a"}, ColumnDataTypes: []string{"INT"}}})
+	assert.EqualError(t, err, "unsupported explain plan result with columns [a]")
+	_, err = ParseQueryPlan(operatorRowsTable())
+	assert.EqualError(t, err, "explain plan result has no operator")
+	_, err = ParseQueryPlan(operatorRowsTable(
+		[]interface{}{"BROKER_REDUCE", json.Number("1"), json.Number("0")},
+		[]interface{}{"SELECT", json.Number("3"), json.Number("2")},
+	))
+	assert.EqualError(t, err, "operator 3 has unknown parent 2")
+	_, err = parseMultistagePlan("LogicalSort\n    LogicalProject")
+	assert.ErrorContains(t, err, "invalid indentation of plan line")
+	_, err = parseMultistagePlan("LogicalSort\nLogicalProject")
+	assert.EqualError(t, err, "plan has several root operators")
+	assert.Equal(t, "", (*QueryPlan)(nil).String())
+}
+
+func TestExplain(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		queries = append(queries, body["sql"])
+		w.Header().Set("Content-Type", "application/json")
+		if len(queries) == 3 {
+			_, err := w.Write([]byte(`{"exceptions":[{"errorCode":150,"message":"SQLParsingError"}]}`))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := w.Write([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["STRING","INT","INT"],"columnNames":["Operator","Operator_Id","Parent_Id"]},"rows":[["BROKER_REDUCE(limit:10)",1,0],["COMBINE_SELECT",2,1]]},"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	plan, err := conn.Explain(context.Background(), "baseballStats", "select playerName from baseballStats limit 10")
+	require.NoError(t, err)
+	assert.Equal(t, "BROKER_REDUCE(limit:10)\n  COMBINE_SELECT\n", plan.String())
+	_, err = conn.Explain(context.Background(), "baseballStats", " explain plan for select 1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"EXPLAIN PLAN FOR select playerName from baseballStats limit 10", " explain plan for select 1"}, queries)
+
+	_, err = conn.Explain(context.Background(), "baseballStats", "select")
+	var queryErr *QueryException
+	require.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, 150, queryErr.ErrorCode)
+}
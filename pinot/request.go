@@ -1,9 +1,111 @@
 package pinot
 
+import (
+	"context"
+	"time"
+)
+
 // Request is used in server request to host multiple pinot query types, like PQL, SQL.
+// Interceptors read it through its accessors and derive modified copies with its With methods.
 type Request struct {
-	queryFormat         string
+	queryFormat string
+	// table is the table the broker is selected for, empty when the query is sent to any broker
+	table               string
 	query               string
 	trace               bool
 	useMultistageEngine bool
+	queryOptions        *QueryOptions
+	// clientQueryID identifies the query on the broker, to cancel it, see Connection.Cancel
+	clientQueryID string
+	// headers are sent as HTTP headers, or gRPC request metadata, along with the query
+	headers map[string]string
+}
+
+// Table returns the table the query was issued for, as passed to the query method, empty when
+// the query is sent to any broker.
+func (r *Request) Table() string {
+	return r.table
+}
+
+// SQL returns the query sent to the broker.
+func (r *Request) SQL() string {
+	return r.query
+}
+
+// QueryOptions returns the query options sent along with the query, the options of the connection
+// merged with the per-query ones, or nil when there are none. They must not be modified,
+// use WithQueryOptions instead.
+func (r *Request) QueryOptions() *QueryOptions {
+	return r.queryOptions
+}
+
+// Trace reports whether the query is executed with tracing enabled.
+func (r *Request) Trace() bool {
+	return r.trace
+}
+
+// UseMultistageEngine reports whether the query is executed on the multi-stage engine.
+func (r *Request) UseMultistageEngine() bool {
+	return r.useMultistageEngine
+}
+
+// ClientQueryID returns the ID identifying the query on the broker, see Connection.Cancel.
+func (r *Request) ClientQueryID() string {
+	return r.clientQueryID
+}
+
+// Headers returns the headers added to the request with WithHeader.
+func (r *Request) Headers() map[string]string {
+	return r.headers
+}
+
+// WithSQL returns a copy of the request executing another query.
+func (r *Request) WithSQL(query string) *Request {
+	clone := r.clone()
+	clone.query = query
+	return clone
+}
+
+// WithQueryOptions returns a copy of the request with options merged into its query options,
+// non-zero values of options taking precedence.
+func (r *Request) WithQueryOptions(options *QueryOptions) *Request {
+	clone := r.clone()
+	clone.queryOptions = r.queryOptions.merge(options)
+	return clone
+}
+
+// WithHeader returns a copy of the request sending an additional header: an HTTP header with the HTTP
+// transport, a request metadata entry with the gRPC transport. It takes precedence over
+// ClientConfig.ExtraHTTPHeader and GrpcConfig.ExtraMetadata.
+func (r *Request) WithHeader(key string, value string) *Request {
+	clone := r.clone()
+	clone.headers = make(map[string]string, len(r.headers)+1)
+	for k, v := range r.headers {
+		clone.headers[k] = v
+	}
+	clone.headers[key] = value
+	return clone
+}
+
+func (r *Request) clone() *Request {
+	clone := *r
+	return &clone
+}
+
+// queryTimeout returns the timeout to send to the broker as the timeoutMs query option.
+// It is the configured timeout, shortened to the remaining time of the context deadline when that is sooner.
+func queryTimeout(ctx context.Context, configured time.Duration) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return configured
+	}
+	remaining := time.Until(deadline)
+	// Keep an almost expired deadline from being sent as timeoutMs=0.
+	if remaining < time.Millisecond {
+		remaining = time.Millisecond
+	}
+	if configured > 0 && configured < remaining {
+		return configured
+	}
+	return remaining
 }
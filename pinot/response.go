@@ -1,13 +1,18 @@
 package pinot
 
 import (
+	"context"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	"reflect"
 	"strconv"
-
-	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
 )
 
 // BrokerResponse is the data structure for broker response.
@@ -30,6 +35,78 @@ type BrokerResponse struct {
 	TimeUsedMs                  int                  `json:"timeUsedMs"`
 	MinConsumingFreshnessTimeMs int64                `json:"minConsumingFreshnessTimeMs"`
 	NumGroupsLimitReached       bool                 `json:"numGroupsLimitReached"`
+
+	RequestID                     string   `json:"requestId,omitempty"`
+	ClientRequestID               string   `json:"clientRequestId,omitempty"`
+	BrokerID                      string   `json:"brokerId,omitempty"`
+	TablesQueried                 []string `json:"tablesQueried,omitempty"`
+	NumRowsResultSet              int      `json:"numRowsResultSet"`
+	PartialResult                 bool     `json:"partialResult"`
+	NumGroupsWarningLimitReached  bool     `json:"numGroupsWarningLimitReached"`
+	MaxRowsInJoinReached          bool     `json:"maxRowsInJoinReached"`
+	MaxRowsInWindowReached        bool     `json:"maxRowsInWindowReached"`
+	NumConsumingSegmentsProcessed int      `json:"numConsumingSegmentsProcessed"`
+	NumConsumingSegmentsMatched   int      `json:"numConsumingSegmentsMatched"`
+	NumSegmentsPrunedByBroker     int      `json:"numSegmentsPrunedByBroker"`
+	NumSegmentsPrunedByServer     int      `json:"numSegmentsPrunedByServer"`
+	NumSegmentsPrunedInvalid      int      `json:"numSegmentsPrunedInvalid"`
+	NumSegmentsPrunedByLimit      int      `json:"numSegmentsPrunedByLimit"`
+	NumSegmentsPrunedByValue      int      `json:"numSegmentsPrunedByValue"`
+	BrokerReduceTimeMs            int64    `json:"brokerReduceTimeMs"`
+
+	OfflineThreadCPUTimeNs                 int64 `json:"offlineThreadCpuTimeNs"`
+	RealtimeThreadCPUTimeNs                int64 `json:"realtimeThreadCpuTimeNs"`
+	OfflineSystemActivitiesCPUTimeNs       int64 `json:"offlineSystemActivitiesCpuTimeNs"`
+	RealtimeSystemActivitiesCPUTimeNs      int64 `json:"realtimeSystemActivitiesCpuTimeNs"`
+	OfflineResponseSerializationCPUTimeNs  int64 `json:"offlineResponseSerializationCpuTimeNs"`
+	RealtimeResponseSerializationCPUTimeNs int64 `json:"realtimeResponseSerializationCpuTimeNs"`
+	OfflineTotalCPUTimeNs                  int64 `json:"offlineTotalCpuTimeNs"`
+	RealtimeTotalCPUTimeNs                 int64 `json:"realtimeTotalCpuTimeNs"`
+	OfflineThreadMemAllocatedBytes         int64 `json:"offlineThreadMemAllocatedBytes"`
+	RealtimeThreadMemAllocatedBytes        int64 `json:"realtimeThreadMemAllocatedBytes"`
+	OfflineResponseSerMemAllocatedBytes    int64 `json:"offlineResponseSerMemAllocatedBytes"`
+	RealtimeResponseSerMemAllocatedBytes   int64 `json:"realtimeResponseSerMemAllocatedBytes"`
+	OfflineTotalMemAllocatedBytes          int64 `json:"offlineTotalMemAllocatedBytes"`
+	RealtimeTotalMemAllocatedBytes         int64 `json:"realtimeTotalMemAllocatedBytes"`
+
+	// StageStats holds the operator statistics of multi-stage engine queries.
+	StageStats *StageStats `json:"stageStats,omitempty"`
+	// Extra holds the fields of the response not modeled by BrokerResponse, such as fields added by
+	// newer brokers, nil when there is none.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// TotalCPUTimeNs returns the CPU time spent by servers on the query, offline and realtime.
+// Brokers not reporting total CPU times have it computed from the thread, system activities and
+// response serialization CPU times.
+func (r *BrokerResponse) TotalCPUTimeNs() int64 {
+	if total := r.OfflineTotalCPUTimeNs + r.RealtimeTotalCPUTimeNs; total != 0 {
+		return total
+	}
+	return r.OfflineThreadCPUTimeNs + r.RealtimeThreadCPUTimeNs +
+		r.OfflineSystemActivitiesCPUTimeNs + r.RealtimeSystemActivitiesCPUTimeNs +
+		r.OfflineResponseSerializationCPUTimeNs + r.RealtimeResponseSerializationCPUTimeNs
+}
+
+// NumSegmentsPruned returns the number of segments pruned by brokers and servers.
+func (r *BrokerResponse) NumSegmentsPruned() int {
+	return r.NumSegmentsPrunedByBroker + r.NumSegmentsPrunedByServer
+}
+
+// UnmarshalJSON decodes a broker response, keeping its unknown fields in Extra.
+func (r *BrokerResponse) UnmarshalJSON(data []byte) error {
+	type brokerResponse BrokerResponse
+	var decoded brokerResponse
+	extra, err := decodeJSONWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*r = BrokerResponse(decoded)
+	r.Extra = extra
+	if r.ResultTable != nil {
+		r.ResultTable.indexColumns()
+	}
+	return nil
 }
 
 // AggregationResult is the data structure for PQL aggregation result
@@ -68,6 +145,11 @@ type Exception struct {
 type ResultTable struct {
 	DataSchema RespSchema      `json:"dataSchema"`
 	Rows       [][]interface{} `json:"rows"`
+
+	// columnIndexes maps column names to indexes, nil for result tables that were not decoded
+	columnIndexes map[string]int
+	// logger logs the conversion errors of accessors, the logger of the connection which received the table
+	logger Logger
 }
 
 // GetRowCount returns how many rows in the ResultTable
@@ -96,24 +178,31 @@ func (r ResultTable) Get(rowIndex int, columnIndex int) interface{} {
 }
 
 // GetString returns a ResultTable string entry given row index and column index
+// Null entries are returned as an empty string; use IsNull or GetNullString to tell them apart.
+// Times and decimals decoded from Arrow responses are formatted like Pinot formats them in JSON responses.
 func (r ResultTable) GetString(rowIndex int, columnIndex int) string {
+	if r.Rows[rowIndex][columnIndex] == nil {
+		return ""
+	}
 	if col, ok := (r.Rows[rowIndex][columnIndex]).(string); ok {
 		return col
 	}
 	if col, ok := (r.Rows[rowIndex][columnIndex]).(json.Number); ok {
 		return string(col)
 	}
+	if col, ok := (r.Rows[rowIndex][columnIndex]).(time.Time); ok {
+		return formatPinotTimestamp(col)
+	}
+	if col, ok := (r.Rows[rowIndex][columnIndex]).(*big.Rat); ok {
+		return formatBigRat(col)
+	}
 	// Handle other common types by converting to string
 	value := r.Rows[rowIndex][columnIndex]
-	log.Debugf("Converting unexpected type %T to string at row %d, column %d: %v", value, rowIndex, columnIndex, value)
+	loggerOrDefault(r.logger).Debug(context.Background(), "Converting unexpected type to string",
+		"type", fmt.Sprintf("%T", value), "row", rowIndex, "column", columnIndex, "value", value)
 	return fmt.Sprintf("%v", value)
 }
 
-// isWithinInt32Range checks if a float64 value is within int32 range
-func isWithinInt32Range(val float64) bool {
-	return val <= float64(math.MaxInt32) && val >= float64(math.MinInt32)
-}
-
 // isWithinInt64Range checks if a float64 value is within int64 range
 func isWithinInt64Range(val float64) bool {
 	return val <= float64(math.MaxInt64) && val >= float64(math.MinInt64)
@@ -125,119 +214,452 @@ func isRangeError(err error) bool {
 	return errors.As(err, &rangeErr) && rangeErr.Err == strconv.ErrRange
 }
 
-// GetInt returns a ResultTable int entry given row index and column index
+// GetInt returns a ResultTable int entry given row index and column index.
+// It returns 0 when the entry is null or not an int, logging an error in the latter case;
+// use GetIntE or GetNullInt to tell these cases apart.
 func (r ResultTable) GetInt(rowIndex int, columnIndex int) int32 {
-	if col, ok := (r.Rows[rowIndex][columnIndex]).(json.Number); ok {
-		val, err := col.Int64()
+	val, err := r.GetIntE(rowIndex, columnIndex)
+	if err != nil {
+		r.logConversionError("int", err)
+	}
+	return val
+}
+
+// GetLong returns a ResultTable long entry given row index and column index.
+// It returns 0 when the entry is null or not a long, logging an error in the latter case;
+// use GetLongE or GetNullLong to tell these cases apart.
+func (r ResultTable) GetLong(rowIndex int, columnIndex int) int64 {
+	val, err := r.GetLongE(rowIndex, columnIndex)
+	if err != nil {
+		r.logConversionError("long", err)
+	}
+	return val
+}
+
+// GetFloat returns a ResultTable float entry given row index and column index.
+// It returns 0 when the entry is null or not a float, logging an error in the latter case;
+// use GetFloatE or GetNullFloat to tell these cases apart.
+func (r ResultTable) GetFloat(rowIndex int, columnIndex int) float32 {
+	val, err := r.GetFloatE(rowIndex, columnIndex)
+	if err != nil {
+		r.logConversionError("float", err)
+	}
+	return val
+}
+
+// GetDouble returns a ResultTable double entry given row index and column index.
+// It returns 0 when the entry is null or not a double, logging an error in the latter case;
+// use GetDoubleE or GetNullDouble to tell these cases apart.
+func (r ResultTable) GetDouble(rowIndex int, columnIndex int) float64 {
+	val, err := r.GetDoubleE(rowIndex, columnIndex)
+	if err != nil {
+		r.logConversionError("double", err)
+	}
+	return val
+}
+
+// logConversionError logs the failure of an accessor returning a zero value. Null entries are expected
+// with null handling enabled, so they are only logged at debug level.
+func (r ResultTable) logConversionError(kind string, err error) {
+	logger := loggerOrDefault(r.logger)
+	if errors.Is(err, ErrNullValue) {
+		logger.Debug(context.Background(), "Converting to "+kind, "error", err)
+		return
+	}
+	logger.Error(context.Background(), "Error converting to "+kind, "error", err)
+}
+
+// IsNull returns whether a ResultTable entry is null. Entries are only null for queries
+// executed with null handling enabled, see QueryOptions.EnableNullHandling.
+func (r ResultTable) IsNull(rowIndex int, columnIndex int) bool {
+	return r.Rows[rowIndex][columnIndex] == nil
+}
+
+// GetNullString returns a ResultTable string entry given row index and column index, invalid when the entry is null.
+func (r ResultTable) GetNullString(rowIndex int, columnIndex int) sql.NullString {
+	if r.IsNull(rowIndex, columnIndex) {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: r.GetString(rowIndex, columnIndex), Valid: true}
+}
+
+// GetNullInt returns a ResultTable int entry given row index and column index, invalid when the entry is null,
+// or an error when the entry is not an int.
+func (r ResultTable) GetNullInt(rowIndex int, columnIndex int) (sql.NullInt32, error) {
+	if r.IsNull(rowIndex, columnIndex) {
+		return sql.NullInt32{}, nil
+	}
+	val, err := r.GetIntE(rowIndex, columnIndex)
+	if err != nil {
+		return sql.NullInt32{}, err
+	}
+	return sql.NullInt32{Int32: val, Valid: true}, nil
+}
+
+// GetNullLong returns a ResultTable long entry given row index and column index, invalid when the entry is null,
+// or an error when the entry is not a long.
+func (r ResultTable) GetNullLong(rowIndex int, columnIndex int) (sql.NullInt64, error) {
+	if r.IsNull(rowIndex, columnIndex) {
+		return sql.NullInt64{}, nil
+	}
+	val, err := r.GetLongE(rowIndex, columnIndex)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	return sql.NullInt64{Int64: val, Valid: true}, nil
+}
+
+// GetNullFloat returns a ResultTable float entry given row index and column index, invalid when the entry is null,
+// or an error when the entry is not a float.
+func (r ResultTable) GetNullFloat(rowIndex int, columnIndex int) (sql.Null[float32], error) {
+	if r.IsNull(rowIndex, columnIndex) {
+		return sql.Null[float32]{}, nil
+	}
+	val, err := r.GetFloatE(rowIndex, columnIndex)
+	if err != nil {
+		return sql.Null[float32]{}, err
+	}
+	return sql.Null[float32]{V: val, Valid: true}, nil
+}
+
+// GetNullDouble returns a ResultTable double entry given row index and column index, invalid when the entry is null,
+// or an error when the entry is not a double.
+func (r ResultTable) GetNullDouble(rowIndex int, columnIndex int) (sql.NullFloat64, error) {
+	if r.IsNull(rowIndex, columnIndex) {
+		return sql.NullFloat64{}, nil
+	}
+	val, err := r.GetDoubleE(rowIndex, columnIndex)
+	if err != nil {
+		return sql.NullFloat64{}, err
+	}
+	return sql.NullFloat64{Float64: val, Valid: true}, nil
+}
+
+// GetStringE returns a ResultTable string entry given row index and column index,
+// or an error matching ErrNullValue when the entry is null. Non-string entries are formatted.
+func (r ResultTable) GetStringE(rowIndex int, columnIndex int) (string, error) {
+	if r.Rows[rowIndex][columnIndex] == nil {
+		return "", r.nullValueError(rowIndex, columnIndex)
+	}
+	return r.GetString(rowIndex, columnIndex), nil
+}
+
+// GetIntE returns a ResultTable int entry given row index and column index, or an error when the entry
+// is null, not a number, not a whole number or out of the int32 range.
+func (r ResultTable) GetIntE(rowIndex int, columnIndex int) (int32, error) {
+	val, err := r.GetLongE(rowIndex, columnIndex)
+	if err != nil {
+		return 0, err
+	}
+	if val > math.MaxInt32 || val < math.MinInt32 {
+		return 0, fmt.Errorf("value %d at row %d, column %d is out of the int range", val, rowIndex, columnIndex)
+	}
+	return int32(val), nil
+}
+
+// GetLongE returns a ResultTable long entry given row index and column index, or an error when the entry
+// is null, not a number, not a whole number or out of the int64 range.
+func (r ResultTable) GetLongE(rowIndex int, columnIndex int) (int64, error) {
+	value, err := r.numberAt(rowIndex, columnIndex)
+	if err != nil {
+		return 0, err
+	}
+	val, err := toInt64(value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %v at row %d, column %d to long: %w", value, rowIndex, columnIndex, err)
+	}
+	return val, nil
+}
+
+// GetFloatE returns a ResultTable float entry given row index and column index, or an error when the entry
+// is null, not a number or out of the float32 range.
+func (r ResultTable) GetFloatE(rowIndex int, columnIndex int) (float32, error) {
+	val, err := r.GetDoubleE(rowIndex, columnIndex)
+	if err != nil {
+		return 0, err
+	}
+	if val > math.MaxFloat32 || val < -math.MaxFloat32 {
+		return 0, fmt.Errorf("value %v at row %d, column %d is out of the float range", val, rowIndex, columnIndex)
+	}
+	return float32(val), nil
+}
+
+// GetDoubleE returns a ResultTable double entry given row index and column index, or an error when the entry
+// is null, not a number or out of the float64 range.
+func (r ResultTable) GetDoubleE(rowIndex int, columnIndex int) (float64, error) {
+	value, err := r.numberAt(rowIndex, columnIndex)
+	if err != nil {
+		return 0, err
+	}
+	val, err := toFloat64(value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %v at row %d, column %d to double: %w", value, rowIndex, columnIndex, err)
+	}
+	if math.IsInf(val, 0) || math.IsNaN(val) {
+		return 0, fmt.Errorf("value %v at row %d, column %d is out of the double range", value, rowIndex, columnIndex)
+	}
+	return val, nil
+}
+
+// GetIntArray returns a ResultTable multi-value entry as ints given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column or an element
+// is not a whole number within the int32 range.
+func (r ResultTable) GetIntArray(rowIndex int, columnIndex int) ([]int32, error) {
+	return arrayAt(r, rowIndex, columnIndex, func(value interface{}) (int32, error) {
+		val, err := toInt64(value)
 		if err != nil {
-			// If Int64() failed, try parsing as float64 and converting to int32
-			// This handles cases where numbers come back as "42.0" instead of "42"
-			floatVal, floatErr := col.Float64()
-			if floatErr != nil {
-				log.Errorf("Error converting to float64: %v", floatErr)
-				return 0
-			}
-			// Check if the float value is within int32 range
-			if !isWithinInt32Range(floatVal) {
-				log.Errorf("Error converting to int: value out of range: %f", floatVal)
-				return 0
-			}
-			// Convert float to int32, checking for whole numbers
-			if floatVal == float64(int32(floatVal)) {
-				return int32(floatVal)
-			}
-			log.Errorf("Error converting to int: %v (value is not a whole number: %f)", err, floatVal)
-			return 0
+			return 0, err
 		}
-		// Check if the value is within int32 range
-		if val > int64(math.MaxInt32) || val < int64(math.MinInt32) {
-			log.Errorf("Error converting to int: value out of range: %d", val)
-			return 0
+		if val > math.MaxInt32 || val < math.MinInt32 {
+			return 0, fmt.Errorf("value %d is out of the int range", val)
 		}
-		return int32(val)
-	}
-	log.Errorf("Error converting to json.Number at row %d, column %d: %v (type: %T)", rowIndex, columnIndex, r.Rows[rowIndex][columnIndex], r.Rows[rowIndex][columnIndex])
-	return 0
+		return int32(val), nil
+	})
 }
 
-// GetLong returns a ResultTable long entry given row index and column index
-func (r ResultTable) GetLong(rowIndex int, columnIndex int) int64 {
-	if col, ok := (r.Rows[rowIndex][columnIndex]).(json.Number); ok {
-		val, err := col.Int64()
+// GetLongArray returns a ResultTable multi-value entry as longs given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column or an element
+// is not a whole number within the int64 range.
+func (r ResultTable) GetLongArray(rowIndex int, columnIndex int) ([]int64, error) {
+	return arrayAt(r, rowIndex, columnIndex, toInt64)
+}
+
+// GetFloatArray returns a ResultTable multi-value entry as floats given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column or an element
+// is not a number within the float32 range.
+func (r ResultTable) GetFloatArray(rowIndex int, columnIndex int) ([]float32, error) {
+	return arrayAt(r, rowIndex, columnIndex, func(value interface{}) (float32, error) {
+		val, err := toFloat64(value)
 		if err != nil {
-			// If Int64() failed, it could be either:
-			// 1. A decimal number like "14859.0" that should be converted
-			// 2. An out-of-range number that should return 0
-			// We can differentiate by checking if the original Int64() error indicates overflow
-			if isRangeError(err) {
-				log.Errorf("Error converting to long: %v", err)
-				return 0
-			}
-
-			// If Int64() failed for other reasons, try parsing as float64 and converting to int64
-			// This handles cases where numbers come back as "14859.0" instead of "14859"
-			floatVal, floatErr := col.Float64()
-			if floatErr != nil {
-				log.Errorf("Error converting to float64: %v", floatErr)
-				return 0
-			}
-			// Check if the float value is within int64 range
-			if !isWithinInt64Range(floatVal) {
-				log.Errorf("Error converting to long: value out of range: %f", floatVal)
-				return 0
-			}
-			// Convert float to int64, checking for whole numbers
-			if floatVal == float64(int64(floatVal)) {
-				return int64(floatVal)
-			}
-			log.Errorf("Error converting to long: %v (value is not a whole number: %f)", err, floatVal)
-			return 0
+			return 0, err
 		}
-		return val
+		if val > math.MaxFloat32 || val < -math.MaxFloat32 {
+			return 0, fmt.Errorf("value %v is out of the float range", val)
+		}
+		return float32(val), nil
+	})
+}
+
+// GetDoubleArray returns a ResultTable multi-value entry as doubles given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column or an element
+// is not a number.
+func (r ResultTable) GetDoubleArray(rowIndex int, columnIndex int) ([]float64, error) {
+	return arrayAt(r, rowIndex, columnIndex, toFloat64)
+}
+
+// GetStringArray returns a ResultTable multi-value entry as strings given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column.
+// Non-string elements are formatted.
+func (r ResultTable) GetStringArray(rowIndex int, columnIndex int) ([]string, error) {
+	return arrayAt(r, rowIndex, columnIndex, func(value interface{}) (string, error) {
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		return fmt.Sprintf("%v", value), nil
+	})
+}
+
+// GetBoolArray returns a ResultTable multi-value entry as booleans given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column or an element
+// is not a boolean.
+func (r ResultTable) GetBoolArray(rowIndex int, columnIndex int) ([]bool, error) {
+	return arrayAt(r, rowIndex, columnIndex, func(value interface{}) (bool, error) {
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+		return false, fmt.Errorf("unexpected value type %T", value)
+	})
+}
+
+// GetTimestampArray returns a ResultTable multi-value entry as times in UTC given row index and column index,
+// see GetTimestampArrayIn.
+func (r ResultTable) GetTimestampArray(rowIndex int, columnIndex int) ([]time.Time, error) {
+	return r.GetTimestampArrayIn(rowIndex, columnIndex, time.UTC)
+}
+
+// GetTimestampArrayIn returns a ResultTable multi-value entry as times in loc given row index and column index,
+// nil when the entry is null, or an error when the column is not a multi-value column or an element
+// is neither a Pinot timestamp nor epoch milliseconds. As with GetTimeIn, loc is the time zone of the broker.
+func (r ResultTable) GetTimestampArrayIn(rowIndex int, columnIndex int, loc *time.Location) ([]time.Time, error) {
+	return arrayAt(r, rowIndex, columnIndex, func(value interface{}) (time.Time, error) {
+		return toTimeIn(value, loc)
+	})
+}
+
+// GetTime returns a ResultTable TIMESTAMP entry given row index and column index as a time in UTC,
+// see GetTimeIn.
+func (r ResultTable) GetTime(rowIndex int, columnIndex int) (time.Time, error) {
+	return r.GetTimeIn(rowIndex, columnIndex, time.UTC)
+}
+
+// GetTimeIn returns a ResultTable TIMESTAMP entry given row index and column index as a time in loc,
+// or an error when the entry is null or is neither a Pinot timestamp nor epoch milliseconds.
+// Pinot formats timestamps without a time zone, in the time zone of the broker: loc is that time zone.
+// Timestamps decoded from Arrow responses are absolute, and only converted to loc.
+func (r ResultTable) GetTimeIn(rowIndex int, columnIndex int, loc *time.Location) (time.Time, error) {
+	value := r.Rows[rowIndex][columnIndex]
+	if value == nil {
+		return time.Time{}, r.nullValueError(rowIndex, columnIndex)
+	}
+	t, err := toTimeIn(value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot convert %v at row %d, column %d to time: %w", value, rowIndex, columnIndex, err)
 	}
-	log.Errorf("Error converting to json.Number at row %d, column %d: %v (type: %T)", rowIndex, columnIndex, r.Rows[rowIndex][columnIndex], r.Rows[rowIndex][columnIndex])
-	return 0
+	return t, nil
 }
 
-// GetFloat returns a ResultTable float entry given row index and column index
-func (r ResultTable) GetFloat(rowIndex int, columnIndex int) float32 {
-	if col, ok := (r.Rows[rowIndex][columnIndex]).(json.Number); ok {
-		val, err := col.Float64()
+// GetBigDecimal returns a ResultTable BIG_DECIMAL entry given row index and column index as a new exact
+// rational number, or an error when the entry is null or not a decimal number.
+func (r ResultTable) GetBigDecimal(rowIndex int, columnIndex int) (*big.Rat, error) {
+	value := r.Rows[rowIndex][columnIndex]
+	if value == nil {
+		return nil, r.nullValueError(rowIndex, columnIndex)
+	}
+	decimal, err := toBigRat(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %v at row %d, column %d to big decimal", value, rowIndex, columnIndex)
+	}
+	return decimal, nil
+}
+
+// GetBytes returns a ResultTable BYTES entry given row index and column index, decoding the hex string
+// Pinot returns, or an error when the entry is null or not hex encoded.
+func (r ResultTable) GetBytes(rowIndex int, columnIndex int) ([]byte, error) {
+	switch value := r.Rows[rowIndex][columnIndex].(type) {
+	case nil:
+		return nil, r.nullValueError(rowIndex, columnIndex)
+	case []byte:
+		return append([]byte(nil), value...), nil
+	case string:
+		decoded, err := hex.DecodeString(value)
 		if err != nil {
-			log.Errorf("Error converting to float: %v", err)
-			return 0
+			return nil, fmt.Errorf("cannot decode bytes at row %d, column %d: %w", rowIndex, columnIndex, err)
 		}
-		// Check if the value is infinity (out of range for float32)
-		if math.IsInf(val, 0) {
-			log.Errorf("Error converting to float: value out of range (infinity): %f", val)
-			return 0
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("value %v at row %d, column %d is a %T, not bytes", value, rowIndex, columnIndex, value)
+	}
+}
+
+// GetJSON unmarshals a ResultTable JSON entry given row index and column index into target,
+// as json.Unmarshal does, or returns an error when the entry is null or cannot be unmarshalled.
+func (r ResultTable) GetJSON(rowIndex int, columnIndex int, target interface{}) error {
+	var data []byte
+	switch value := r.Rows[rowIndex][columnIndex].(type) {
+	case nil:
+		return r.nullValueError(rowIndex, columnIndex)
+	case string:
+		data = []byte(value)
+	default:
+		// Entries may have been decoded already, for instance MAP entries.
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return fmt.Errorf("cannot encode %T at row %d, column %d: %w", value, rowIndex, columnIndex, err)
 		}
-		// Check if the value is too large to fit in float32 (but not infinity)
-		if val > float64(math.MaxFloat32) || val < -float64(math.MaxFloat32) {
-			log.Errorf("Error converting to float: value out of range: %f", val)
-			return 0
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("cannot unmarshal JSON at row %d, column %d: %w", rowIndex, columnIndex, err)
+	}
+	return nil
+}
+
+// GetMap returns a ResultTable MAP entry given row index and column index, with json.Number numeric values
+// whichever transport produced the result, or an error when the entry is null or not a map.
+func (r ResultTable) GetMap(rowIndex int, columnIndex int) (map[string]interface{}, error) {
+	switch value := r.Rows[rowIndex][columnIndex].(type) {
+	case nil:
+		return nil, r.nullValueError(rowIndex, columnIndex)
+	case map[string]interface{}:
+		return value, nil
+	case string:
+		var output map[string]interface{}
+		if err := decodeJSONWithNumber([]byte(value), &output); err != nil {
+			return nil, fmt.Errorf("cannot decode map at row %d, column %d: %w", rowIndex, columnIndex, err)
 		}
-		return float32(val)
+		return output, nil
+	default:
+		return nil, fmt.Errorf("value %v at row %d, column %d is a %T, not a map", value, rowIndex, columnIndex, value)
 	}
-	log.Errorf("Error converting to json.Number at row %d, column %d: %v (type: %T)", rowIndex, columnIndex, r.Rows[rowIndex][columnIndex], r.Rows[rowIndex][columnIndex])
-	return 0
 }
 
-// GetDouble returns a ResultTable double entry given row index and column index
-func (r ResultTable) GetDouble(rowIndex int, columnIndex int) float64 {
-	if col, ok := (r.Rows[rowIndex][columnIndex]).(json.Number); ok {
-		val, err := col.Float64()
+// arrayAt converts the elements of a multi-value entry. The JSON transport decodes multi-value entries
+// as []interface{} and the Arrow one as typed slices, so elements are accessed through reflection.
+func arrayAt[T any](r ResultTable, rowIndex int, columnIndex int, convert func(interface{}) (T, error)) ([]T, error) {
+	columnType := r.GetColumnDataType(columnIndex)
+	if !strings.HasSuffix(strings.ToUpper(columnType), "_ARRAY") {
+		return nil, fmt.Errorf("column %d is a %s column, not a multi-value column", columnIndex, columnType)
+	}
+	value := r.Rows[rowIndex][columnIndex]
+	if value == nil {
+		return nil, nil
+	}
+	values := reflect.ValueOf(value)
+	if values.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("value %v at row %d, column %d is a %T, not an array", value, rowIndex, columnIndex, value)
+	}
+	output := make([]T, values.Len())
+	for i := range output {
+		val, err := convert(values.Index(i).Interface())
 		if err != nil {
-			log.Errorf("Error converting to double: %v", err)
-			return 0
+			return nil, fmt.Errorf("cannot convert element %d at row %d, column %d: %w", i, rowIndex, columnIndex, err)
 		}
-		// Check if the value is infinity (out of range for float64)
-		if math.IsInf(val, 0) {
-			log.Errorf("Error converting to double: value out of range (infinity): %f", val)
-			return 0
+		output[i] = val
+	}
+	return output, nil
+}
+
+// numberAt returns a numeric entry, or an error when it is null or not a json.Number.
+func (r ResultTable) numberAt(rowIndex int, columnIndex int) (json.Number, error) {
+	switch value := r.Rows[rowIndex][columnIndex].(type) {
+	case nil:
+		return "", r.nullValueError(rowIndex, columnIndex)
+	case json.Number:
+		return value, nil
+	default:
+		return "", fmt.Errorf("value %v at row %d, column %d is a %T, not a json.Number", value, rowIndex, columnIndex, value)
+	}
+}
+
+func (r ResultTable) nullValueError(rowIndex int, columnIndex int) error {
+	return fmt.Errorf("%w at row %d, column %d", ErrNullValue, rowIndex, columnIndex)
+}
+
+// ColumnIndex returns the index of the column with the given name, or an error matching ErrColumnNotFound.
+// The name-to-index map is built once when the broker response is decoded.
+func (r ResultTable) ColumnIndex(name string) (int, error) {
+	if r.columnIndexes != nil {
+		if index, ok := r.columnIndexes[name]; ok {
+			return index, nil
+		}
+		return -1, fmt.Errorf("%w: %s", ErrColumnNotFound, name)
+	}
+	for index, columnName := range r.DataSchema.ColumnNames {
+		if columnName == name {
+			return index, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: %s", ErrColumnNotFound, name)
+}
+
+// GetByName returns a ResultTable entry given row index and column name,
+// or an error matching ErrColumnNotFound when the result has no such column.
+func (r ResultTable) GetByName(rowIndex int, columnName string) (interface{}, error) {
+	columnIndex, err := r.ColumnIndex(columnName)
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(rowIndex, columnIndex), nil
+}
+
+// indexColumns builds the name-to-index map of the columns, keeping the first of duplicated names.
+func (r *ResultTable) indexColumns() {
+	r.columnIndexes = make(map[string]int, len(r.DataSchema.ColumnNames))
+	for index, name := range r.DataSchema.ColumnNames {
+		if _, ok := r.columnIndexes[name]; !ok {
+			r.columnIndexes[name] = index
 		}
-		return val
 	}
-	log.Errorf("Error converting to json.Number at row %d, column %d: %v (type: %T)", rowIndex, columnIndex, r.Rows[rowIndex][columnIndex], r.Rows[rowIndex][columnIndex])
-	return 0
 }
@@ -1,11 +1,15 @@
 package pinot
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSqlSelectionQueryResponse(t *testing.T) {
@@ -368,3 +372,371 @@ func TestResultTableBoundaryValues(t *testing.T) {
 	assert.True(t, result2 != 0, "float32 min value should not be converted to 0")
 	assert.Equal(t, float64(-1.7976931348623157e+308), resultTable.GetDouble(1, 3))
 }
+
+func TestResultTableErrorAccessors(t *testing.T) {
+	resultTable := ResultTable{
+		DataSchema: RespSchema{
+			ColumnDataTypes: []string{"INT", "LONG", "DOUBLE", "STRING", "INT"},
+			ColumnNames:     []string{"int_val", "long_val", "double_val", "string_val", "null_val"},
+		},
+		Rows: [][]interface{}{
+			{json.Number("0"), json.Number("12345.0"), json.Number("1.5"), "text", nil},
+			{json.Number("2147483648"), json.Number("1.5"), json.Number("1e309"), json.Number("7"), nil},
+		},
+	}
+
+	intVal, err := resultTable.GetIntE(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), intVal)
+	longVal, err := resultTable.GetLongE(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12345), longVal)
+	floatVal, err := resultTable.GetFloatE(0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, float32(1.5), floatVal)
+	doubleVal, err := resultTable.GetDoubleE(0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, doubleVal)
+	stringVal, err := resultTable.GetStringE(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "7", stringVal)
+
+	// Null entries
+	_, err = resultTable.GetIntE(0, 4)
+	assert.ErrorIs(t, err, ErrNullValue)
+	assert.EqualError(t, err, "null value at row 0, column 4")
+	_, err = resultTable.GetLongE(0, 4)
+	assert.ErrorIs(t, err, ErrNullValue)
+	_, err = resultTable.GetFloatE(0, 4)
+	assert.ErrorIs(t, err, ErrNullValue)
+	_, err = resultTable.GetDoubleE(0, 4)
+	assert.ErrorIs(t, err, ErrNullValue)
+	_, err = resultTable.GetStringE(0, 4)
+	assert.ErrorIs(t, err, ErrNullValue)
+
+	// Type mismatches and out of range values
+	_, err = resultTable.GetLongE(0, 3)
+	assert.EqualError(t, err, "value text at row 0, column 3 is a string, not a json.Number")
+	_, err = resultTable.GetIntE(1, 0)
+	assert.EqualError(t, err, "value 2147483648 at row 1, column 0 is out of the int range")
+	_, err = resultTable.GetLongE(1, 1)
+	assert.ErrorContains(t, err, "cannot convert 1.5 at row 1, column 1 to long")
+	_, err = resultTable.GetDoubleE(1, 2)
+	assert.Error(t, err)
+	_, err = resultTable.GetFloatE(1, 2)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNullValue)
+}
+
+func TestResultTableColumnIndex(t *testing.T) {
+	var resp BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["STRING","LONG","LONG"],"columnNames":["name","runs","runs"]},"rows":[["Babe",714,1]]}}`), &resp))
+	require.NotNil(t, resp.ResultTable.columnIndexes)
+
+	literal := ResultTable{DataSchema: resp.ResultTable.DataSchema, Rows: resp.ResultTable.Rows}
+	for _, table := range []ResultTable{*resp.ResultTable, literal} {
+		index, err := table.ColumnIndex("runs")
+		require.NoError(t, err)
+		assert.Equal(t, 1, index)
+		value, err := table.GetByName(0, "name")
+		require.NoError(t, err)
+		assert.Equal(t, "Babe", value)
+		value, err = table.GetByName(0, "runs")
+		require.NoError(t, err)
+		assert.Equal(t, json.Number("714"), value)
+
+		index, err = table.ColumnIndex("Name")
+		assert.ErrorIs(t, err, ErrColumnNotFound)
+		assert.Equal(t, -1, index)
+		_, err = table.GetByName(0, "missing")
+		assert.EqualError(t, err, "column not found: missing")
+	}
+}
+
+func TestResultTableNullAccessors(t *testing.T) {
+	var resp BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["STRING","INT","LONG","FLOAT","DOUBLE"],"columnNames":["s","i","l","f","d"]},"rows":[[null,null,null,null,null],["a",1,2,1.5,2.5]]}}`), &resp))
+	table := resp.ResultTable
+
+	for col := 0; col < table.GetColumnCount(); col++ {
+		assert.True(t, table.IsNull(0, col))
+		assert.False(t, table.IsNull(1, col))
+	}
+	assert.Equal(t, "", table.GetString(0, 0))
+	assert.Equal(t, int64(0), table.GetLong(0, 2))
+
+	assert.Equal(t, sql.NullString{}, table.GetNullString(0, 0))
+	assert.Equal(t, sql.NullString{String: "a", Valid: true}, table.GetNullString(1, 0))
+
+	nullInt, err := table.GetNullInt(0, 1)
+	require.NoError(t, err)
+	assert.False(t, nullInt.Valid)
+	nullInt, err = table.GetNullInt(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullInt32{Int32: 1, Valid: true}, nullInt)
+
+	nullLong, err := table.GetNullLong(0, 2)
+	require.NoError(t, err)
+	assert.False(t, nullLong.Valid)
+	nullLong, err = table.GetNullLong(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullInt64{Int64: 2, Valid: true}, nullLong)
+
+	nullFloat, err := table.GetNullFloat(0, 3)
+	require.NoError(t, err)
+	assert.False(t, nullFloat.Valid)
+	nullFloat, err = table.GetNullFloat(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, sql.Null[float32]{V: 1.5, Valid: true}, nullFloat)
+
+	nullDouble, err := table.GetNullDouble(0, 4)
+	require.NoError(t, err)
+	assert.False(t, nullDouble.Valid)
+	nullDouble, err = table.GetNullDouble(1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullFloat64{Float64: 2.5, Valid: true}, nullDouble)
+
+	// Conversion failures are still reported for non-null entries.
+	_, err = table.GetNullInt(1, 0)
+	assert.Error(t, err)
+	_, err = table.GetNullLong(1, 0)
+	assert.Error(t, err)
+	_, err = table.GetNullFloat(1, 0)
+	assert.Error(t, err)
+	_, err = table.GetNullDouble(1, 0)
+	assert.Error(t, err)
+}
+
+func TestResultTableArrayAccessors(t *testing.T) {
+	schema := RespSchema{
+		ColumnNames:     []string{"ints", "longs", "floats", "doubles", "strings", "bools", "timestamps", "name"},
+		ColumnDataTypes: []string{"INT_ARRAY", "LONG_ARRAY", "FLOAT_ARRAY", "DOUBLE_ARRAY", "STRING_ARRAY", "BOOLEAN_ARRAY", "TIMESTAMP_ARRAY", "STRING"},
+	}
+	var resp BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["INT_ARRAY","LONG_ARRAY","FLOAT_ARRAY","DOUBLE_ARRAY","STRING_ARRAY","BOOLEAN_ARRAY","TIMESTAMP_ARRAY","STRING"],"columnNames":["ints","longs","floats","doubles","strings","bools","timestamps","name"]},"rows":[[[1,2],[3000000000],[1.5],[2.5,3],["a","b"],[true,false],["2024-01-02 03:04:05.0"],"x"],[null,[],null,null,null,null,null,"y"]]}}`), &resp))
+	// The Arrow transport decodes the same rows into typed slices.
+	arrowTable := ResultTable{DataSchema: schema, Rows: [][]interface{}{
+		{[]int{1, 2}, []int64{3000000000}, []float32{1.5}, []float64{2.5, 3}, []string{"a", "b"}, []bool{true, false},
+			[]string{"2024-01-02 03:04:05.0"}, "x"},
+		{nil, []int64{}, nil, nil, nil, nil, nil, "y"},
+	}}
+
+	for name, table := range map[string]ResultTable{"json": *resp.ResultTable, "arrow": arrowTable} {
+		t.Run(name, func(t *testing.T) {
+			ints, err := table.GetIntArray(0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, []int32{1, 2}, ints)
+			longs, err := table.GetLongArray(0, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{3000000000}, longs)
+			floats, err := table.GetFloatArray(0, 2)
+			require.NoError(t, err)
+			assert.Equal(t, []float32{1.5}, floats)
+			doubles, err := table.GetDoubleArray(0, 3)
+			require.NoError(t, err)
+			assert.Equal(t, []float64{2.5, 3}, doubles)
+			strs, err := table.GetStringArray(0, 4)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "b"}, strs)
+			bools, err := table.GetBoolArray(0, 5)
+			require.NoError(t, err)
+			assert.Equal(t, []bool{true, false}, bools)
+			timestamps, err := table.GetTimestampArray(0, 6)
+			require.NoError(t, err)
+			assert.Equal(t, []time.Time{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, timestamps)
+			brokerZone := time.FixedZone("UTC+8", 8*60*60)
+			timestamps, err = table.GetTimestampArrayIn(0, 6, brokerZone)
+			require.NoError(t, err)
+			assert.Equal(t, []time.Time{time.Date(2024, 1, 2, 3, 4, 5, 0, brokerZone)}, timestamps)
+
+			ints, err = table.GetIntArray(1, 0)
+			require.NoError(t, err)
+			assert.Nil(t, ints)
+			longs, err = table.GetLongArray(1, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{}, longs)
+
+			_, err = table.GetIntArray(0, 1)
+			assert.ErrorContains(t, err, "cannot convert element 0 at row 0, column 1: value 3000000000 is out of the int range")
+			_, err = table.GetBoolArray(0, 0)
+			assert.Error(t, err)
+			_, err = table.GetTimestampArray(0, 4)
+			assert.ErrorContains(t, err, `invalid timestamp "a"`)
+			_, err = table.GetStringArray(0, 7)
+			assert.EqualError(t, err, "column 7 is a STRING column, not a multi-value column")
+		})
+	}
+}
+
+func TestResultTableGetTimestampArrayInArrowTimes(t *testing.T) {
+	// Arrow timestamps are absolute: the same instant as the JSON timestamps of a broker in UTC+8.
+	brokerZone := time.FixedZone("UTC+8", 8*60*60)
+	table := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"timestamps"}, ColumnDataTypes: []string{"TIMESTAMP_ARRAY"}},
+		Rows:       [][]interface{}{{[]time.Time{time.Date(2024, 1, 1, 19, 4, 5, 0, time.UTC)}}},
+	}
+	timestamps, err := table.GetTimestampArrayIn(0, 0, brokerZone)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(2024, 1, 2, 3, 4, 5, 0, brokerZone)}, timestamps)
+}
+
+func TestResultTableTypedAccessors(t *testing.T) {
+	var resp BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["TIMESTAMP","BIG_DECIMAL","BYTES","JSON","MAP"],"columnNames":["ts","amount","raw","doc","attrs"]},"rows":[["2024-01-02 03:04:05.5","12345678901234567890.123456789","0a0bff","{\"name\":\"a\",\"tags\":[1,2]}",{"k":1,"s":"v"}],[null,null,null,null,null],["bad","1.2.3","xyz","{",true]]}}`), &resp))
+	table := resp.ResultTable
+
+	ts, err := table.GetTime(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC), ts)
+	tokyo := time.FixedZone("JST", 9*3600)
+	ts, err = table.GetTimeIn(0, 0, tokyo)
+	require.NoError(t, err)
+	assert.Equal(t, tokyo, ts.Location())
+	assert.True(t, time.Date(2024, 1, 1, 18, 4, 5, 500000000, time.UTC).Equal(ts))
+	millis := ResultTable{Rows: [][]interface{}{{json.Number("1704164645000")}}}
+	ts, err = millis.GetTimeIn(0, 0, tokyo)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 12, 4, 5, 0, tokyo), ts)
+
+	decimal, err := table.GetBigDecimal(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678901234567890.123456789", decimal.FloatString(9))
+
+	raw, err := table.GetBytes(0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x0b, 0xff}, raw)
+
+	var doc struct {
+		Name string `json:"name"`
+		Tags []int  `json:"tags"`
+	}
+	require.NoError(t, table.GetJSON(0, 3, &doc))
+	assert.Equal(t, "a", doc.Name)
+	assert.Equal(t, []int{1, 2}, doc.Tags)
+	var attrs map[string]interface{}
+	require.NoError(t, table.GetJSON(0, 4, &attrs))
+	assert.Equal(t, map[string]interface{}{"k": float64(1), "s": "v"}, attrs)
+
+	attrs, err = table.GetMap(0, 4)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"k": json.Number("1"), "s": "v"}, attrs)
+	// MAP entries decoded from gRPC blocks.
+	buf := &bytes.Buffer{}
+	writeInt32(t, buf, 2)
+	for _, entry := range [][2]string{{"k", "1"}, {"s", `"v"`}} {
+		writeSchemaString(t, buf, entry[0])
+		writeInt32(t, buf, len(entry[1]))
+		buf.WriteString(entry[1])
+	}
+	grpcMap, err := decodeMap(buf.Bytes())
+	require.NoError(t, err)
+	grpcTable := ResultTable{Rows: [][]interface{}{{grpcMap}, {`{"k":1,"s":"v"}`}}}
+	for row := 0; row < 2; row++ {
+		attrs, err = grpcTable.GetMap(row, 0)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"k": json.Number("1"), "s": "v"}, attrs)
+	}
+
+	_, err = table.GetTime(1, 0)
+	assert.ErrorIs(t, err, ErrNullValue)
+	_, err = table.GetBigDecimal(1, 1)
+	assert.ErrorIs(t, err, ErrNullValue)
+	_, err = table.GetBytes(1, 2)
+	assert.ErrorIs(t, err, ErrNullValue)
+	assert.ErrorIs(t, table.GetJSON(1, 3, &doc), ErrNullValue)
+	_, err = table.GetMap(1, 4)
+	assert.ErrorIs(t, err, ErrNullValue)
+
+	_, err = table.GetTime(2, 0)
+	assert.ErrorContains(t, err, `invalid timestamp "bad"`)
+	_, err = table.GetBigDecimal(2, 1)
+	assert.EqualError(t, err, "cannot convert 1.2.3 at row 2, column 1 to big decimal")
+	_, err = table.GetBytes(2, 2)
+	assert.ErrorContains(t, err, "cannot decode bytes at row 2, column 2")
+	assert.ErrorContains(t, table.GetJSON(2, 3, &doc), "cannot unmarshal JSON at row 2, column 3")
+	_, err = table.GetMap(2, 4)
+	assert.EqualError(t, err, "value true at row 2, column 4 is a bool, not a map")
+}
+
+func TestBrokerResponseStatistics(t *testing.T) {
+	var resp BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{
+		"resultTable": {"dataSchema": {"columnDataTypes": ["LONG"], "columnNames": ["cnt"]}, "rows": [[3]]},
+		"exceptions": [],
+		"requestId": "236490978000000006", "clientRequestId": "my-query", "brokerId": "Broker_127.0.0.1_8099",
+		"tablesQueried": ["baseballStats"], "numRowsResultSet": 1, "partialResult": true,
+		"numGroupsWarningLimitReached": true, "maxRowsInJoinReached": true, "maxRowsInWindowReached": false,
+		"numConsumingSegmentsProcessed": 2, "numConsumingSegmentsMatched": 1,
+		"numSegmentsPrunedByBroker": 3, "numSegmentsPrunedByServer": 4, "numSegmentsPrunedInvalid": 5,
+		"numSegmentsPrunedByLimit": 6, "numSegmentsPrunedByValue": 7, "brokerReduceTimeMs": 8,
+		"offlineThreadCpuTimeNs": 100, "realtimeThreadCpuTimeNs": 200,
+		"offlineSystemActivitiesCpuTimeNs": 10, "realtimeSystemActivitiesCpuTimeNs": 20,
+		"offlineResponseSerializationCpuTimeNs": 1, "realtimeResponseSerializationCpuTimeNs": 2,
+		"offlineThreadMemAllocatedBytes": 1024, "realtimeTotalMemAllocatedBytes": 2048,
+		"stageStats": {
+			"type": "MAILBOX_RECEIVE", "executionTimeMs": 12, "emittedRows": 1, "fanIn": 2,
+			"children": [{
+				"type": "MAILBOX_SEND", "stage": 1, "parallelism": 2, "executionTimeMs": 10, "emittedRows": 1,
+				"children": [{"type": "LEAF", "table": "baseballStats", "executionTimeMs": 9, "emittedRows": 3, "numDocsScanned": 97889}]
+			}]
+		},
+		"numServersQueried": 1,
+		"pools": [0, 1],
+		"someFutureStat": {"a": 1}
+	}`), &resp))
+
+	assert.Equal(t, "236490978000000006", resp.RequestID)
+	assert.Equal(t, "my-query", resp.ClientRequestID)
+	assert.Equal(t, "Broker_127.0.0.1_8099", resp.BrokerID)
+	assert.Equal(t, []string{"baseballStats"}, resp.TablesQueried)
+	assert.Equal(t, 1, resp.NumRowsResultSet)
+	assert.True(t, resp.PartialResult)
+	assert.True(t, resp.NumGroupsWarningLimitReached)
+	assert.True(t, resp.MaxRowsInJoinReached)
+	assert.False(t, resp.MaxRowsInWindowReached)
+	assert.Equal(t, 2, resp.NumConsumingSegmentsProcessed)
+	assert.Equal(t, 1, resp.NumConsumingSegmentsMatched)
+	assert.Equal(t, 3, resp.NumSegmentsPrunedByBroker)
+	assert.Equal(t, 4, resp.NumSegmentsPrunedByServer)
+	assert.Equal(t, 5, resp.NumSegmentsPrunedInvalid)
+	assert.Equal(t, 6, resp.NumSegmentsPrunedByLimit)
+	assert.Equal(t, 7, resp.NumSegmentsPrunedByValue)
+	assert.Equal(t, 7, resp.NumSegmentsPruned())
+	assert.Equal(t, int64(8), resp.BrokerReduceTimeMs)
+	assert.Equal(t, int64(100), resp.OfflineThreadCPUTimeNs)
+	assert.Equal(t, int64(200), resp.RealtimeThreadCPUTimeNs)
+	assert.Equal(t, int64(333), resp.TotalCPUTimeNs())
+	assert.Equal(t, int64(1024), resp.OfflineThreadMemAllocatedBytes)
+	assert.Equal(t, int64(2048), resp.RealtimeTotalMemAllocatedBytes)
+	assert.Equal(t, 1, resp.NumServersQueried)
+	assert.Equal(t, int64(3), resp.ResultTable.GetLong(0, 0))
+
+	require.NotNil(t, resp.StageStats)
+	assert.Equal(t, "MAILBOX_RECEIVE", resp.StageStats.Type)
+	assert.Equal(t, int64(12), resp.StageStats.ExecutionTimeMs)
+	assert.Equal(t, map[string]json.RawMessage{"fanIn": json.RawMessage(`2`)}, resp.StageStats.Extra)
+	require.Len(t, resp.StageStats.Children, 1)
+	send := resp.StageStats.Children[0]
+	assert.Equal(t, 1, send.Stage)
+	assert.Equal(t, 2, send.Parallelism)
+	assert.Nil(t, send.Extra)
+	require.Len(t, send.Children, 1)
+	assert.Equal(t, "baseballStats", send.Children[0].Table)
+	assert.Equal(t, int64(3), send.Children[0].EmittedRows)
+	assert.Equal(t, map[string]json.RawMessage{"numDocsScanned": json.RawMessage(`97889`)}, send.Children[0].Extra)
+
+	assert.Equal(t, map[string]json.RawMessage{
+		"pools":          json.RawMessage(`[0, 1]`),
+		"someFutureStat": json.RawMessage(`{"a": 1}`),
+	}, resp.Extra)
+
+	var known BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{"exceptions":[],"NUMDOCSSCANNED":5,"offlineTotalCpuTimeNs":7,"realtimeTotalCpuTimeNs":8,"offlineThreadCpuTimeNs":1}`), &known))
+	assert.Nil(t, known.Extra)
+	assert.Equal(t, int64(5), known.NumDocsScanned)
+	assert.Equal(t, int64(15), known.TotalCPUTimeNs())
+
+	var invalid BrokerResponse
+	err := decodeJSONWithNumber([]byte(`{"numDocsScanned":"many"}`), &invalid)
+	assert.ErrorContains(t, err, "failed to decode field numDocsScanned")
+}
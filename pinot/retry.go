@@ -0,0 +1,178 @@
+package pinot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 2 * time.Second
+	defaultRetryMultiplier     = 2.0
+)
+
+// RetryableErrors is a set of error classes a RetryPolicy retries on, combined with |.
+type RetryableErrors int
+
+const (
+	// RetryOnConnectionError retries when the broker could not be dialed, refused, reset or closed the connection
+	// before answering
+	RetryOnConnectionError RetryableErrors = 1 << iota
+	// RetryOnServerError retries when the broker answered with a 5xx HTTP status or an internal gRPC error
+	RetryOnServerError
+	// RetryOnTimeout retries when the transport timeout (HTTPTimeout or GrpcConfig.Timeout) expired,
+	// or the broker answered with a gateway timeout. Expiry of the caller's context is never retried.
+	RetryOnTimeout
+	// RetryOnTooManyRequests retries when the broker throttled the query
+	RetryOnTooManyRequests
+
+	// defaultRetryOn is used when RetryPolicy.RetryOn is zero
+	defaultRetryOn = RetryOnConnectionError | RetryOnServerError
+)
+
+// RetryPolicy configures retries of queries that failed on a broker.
+// Only transport failures are retried: exceptions reported by Pinot in a successful response are not.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, the first one included. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry - defaults to 100ms
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponentially growing delay between retries - defaults to 2s
+	MaxBackoff time.Duration
+	// BackoffMultiplier is the growth factor of the delay between retries - defaults to 2
+	BackoffMultiplier float64
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1, to spread
+	// the retries of concurrent queries. With 0.5, a 100ms delay becomes a random delay in [50ms, 100ms].
+	Jitter float64
+	// RetryOn is the set of error classes to retry - defaults to RetryOnConnectionError | RetryOnServerError
+	RetryOn RetryableErrors
+	// AvoidFailedBrokers retries on brokers serving the table that did not fail yet for the query,
+	// falling back to any of them once they all failed
+	AvoidFailedBrokers bool
+}
+
+// maxAttempts returns the number of attempts allowed by the policy, 1 for a nil policy.
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// shouldRetry reports whether a query failing with err at the given attempt is retried.
+func (p *RetryPolicy) shouldRetry(ctx context.Context, attempt int, err error) bool {
+	if attempt >= p.maxAttempts() || ctx.Err() != nil {
+		return false
+	}
+	retryOn := p.RetryOn
+	if retryOn == 0 {
+		retryOn = defaultRetryOn
+	}
+	return retryOn&classifyError(err) != 0
+}
+
+// backoff returns the delay before the retry following the given attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial, maxBackoff, multiplier := p.InitialBackoff, p.MaxBackoff, p.BackoffMultiplier
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+	delay := math.Min(float64(initial)*math.Pow(multiplier, float64(attempt-1)), float64(maxBackoff))
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		// #nosec G404
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// wait sleeps for the backoff following the given attempt, returning early with the error of ctx when it is done.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// classifyError returns the retryable class of a transport error, 0 when it must not be retried.
+func classifyError(err error) RetryableErrors {
+	var httpErr *BrokerHTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return RetryOnTooManyRequests
+		case errors.Is(httpErr, ErrQueryTimeout):
+			return RetryOnTimeout
+		case httpErr.StatusCode >= http.StatusInternalServerError:
+			return RetryOnServerError
+		}
+		return 0
+	}
+	if errors.Is(markTimeout(err), ErrQueryTimeout) {
+		return RetryOnTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return 0
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		switch s.Code() {
+		case codes.Unavailable:
+			return RetryOnConnectionError
+		case codes.ResourceExhausted:
+			return RetryOnTooManyRequests
+		case codes.DeadlineExceeded:
+			return RetryOnTimeout
+		case codes.Internal, codes.Aborted:
+			return RetryOnServerError
+		}
+		return 0
+	}
+	// Only failures to reach the broker are retried: TLS verification or malformed URL errors would fail again.
+	var opErr *net.OpError
+	if (errors.As(err, &opErr) && opErr.Op == "dial") ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || isConnectionClosed(err) {
+		return RetryOnConnectionError
+	}
+	return 0
+}
+
+// isConnectionClosed reports whether the HTTP client got no response because the broker closed the connection,
+// like a broker shutting down does with its keep-alive connections. Truncated response bodies are not retried.
+func isConnectionClosed(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && (errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF))
+}
+
+// excludeBrokers returns the brokers not in failed, or all of them when they all failed.
+func excludeBrokers(brokers []string, failed map[string]bool) []string {
+	remaining := make([]string, 0, len(brokers))
+	for _, broker := range brokers {
+		if !failed[broker] {
+			remaining = append(remaining, broker)
+		}
+	}
+	if len(remaining) == 0 {
+		return brokers
+	}
+	return remaining
+}
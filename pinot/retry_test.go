@@ -0,0 +1,243 @@
+package pinot
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected RetryableErrors
+	}{
+		{"server error", &BrokerHTTPError{StatusCode: http.StatusServiceUnavailable}, RetryOnServerError},
+		{"gateway timeout", &BrokerHTTPError{StatusCode: http.StatusGatewayTimeout}, RetryOnTimeout},
+		{"too many requests", &BrokerHTTPError{StatusCode: http.StatusTooManyRequests}, RetryOnTooManyRequests},
+		{"client error", &BrokerHTTPError{StatusCode: http.StatusBadRequest}, 0},
+		{"deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), RetryOnTimeout},
+		{"canceled", fmt.Errorf("wrapped: %w", context.Canceled), 0},
+		{"connection refused", fmt.Errorf("wrapped: %w", syscall.ECONNREFUSED), RetryOnConnectionError},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, RetryOnConnectionError},
+		{"dial error", &url.Error{Op: "Post", URL: "http://broker:8000/query/sql",
+			Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "broker"}}}, RetryOnConnectionError},
+		{"net timeout", &url.Error{Op: "Post", URL: "http://broker:8000/query/sql",
+			Err: &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}}, RetryOnTimeout},
+		{"tls verification", &url.Error{Op: "Post", URL: "https://broker:8000/query/sql",
+			Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, 0},
+		{"unsupported scheme", &url.Error{Op: "Post", URL: "ftp://broker:8000/query/sql",
+			Err: errors.New(`unsupported protocol scheme "ftp"`)}, 0},
+		{"grpc unavailable", fmt.Errorf("wrapped: %w", status.Error(codes.Unavailable, "down")), RetryOnConnectionError},
+		{"grpc internal", status.Error(codes.Internal, "boom"), RetryOnServerError},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, "bad"), 0},
+		{"connection closed", &url.Error{Op: "Post", URL: "http://broker:8000/query/sql", Err: io.EOF}, RetryOnConnectionError},
+		{"connection closed while reading headers", &url.Error{Op: "Post", URL: "http://broker:8000/query/sql",
+			Err: io.ErrUnexpectedEOF}, RetryOnConnectionError},
+		{"truncated body", fmt.Errorf("unable to read Pinot response. %w", io.ErrUnexpectedEOF), 0},
+		{"other", errors.New("unable to unmarshal json response"), 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifyError(tc.err))
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	var nilPolicy *RetryPolicy
+	serverErr := &BrokerHTTPError{StatusCode: http.StatusBadGateway}
+	assert.False(t, nilPolicy.shouldRetry(context.Background(), 1, serverErr))
+
+	policy := &RetryPolicy{MaxAttempts: 3}
+	assert.True(t, policy.shouldRetry(context.Background(), 1, serverErr))
+	assert.True(t, policy.shouldRetry(context.Background(), 2, serverErr))
+	assert.False(t, policy.shouldRetry(context.Background(), 3, serverErr))
+	assert.False(t, policy.shouldRetry(context.Background(), 1, context.DeadlineExceeded))
+
+	policy.RetryOn = RetryOnTimeout
+	assert.False(t, policy.shouldRetry(context.Background(), 1, serverErr))
+	assert.True(t, policy.shouldRetry(context.Background(), 1, context.DeadlineExceeded))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, policy.shouldRetry(ctx, 1, context.DeadlineExceeded))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 40*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, policy.backoff(4))
+
+	assert.Equal(t, defaultRetryInitialBackoff, (&RetryPolicy{}).backoff(1))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(2)
+		assert.GreaterOrEqual(t, delay, 10*time.Millisecond)
+		assert.LessOrEqual(t, delay, 20*time.Millisecond)
+	}
+}
+
+func TestExcludeBrokers(t *testing.T) {
+	brokers := []string{"b1", "b2", "b3"}
+	assert.Equal(t, []string{"b1", "b3"}, excludeBrokers(brokers, map[string]bool{"b2": true}))
+	assert.Equal(t, brokers, excludeBrokers(brokers, map[string]bool{"b1": true, "b2": true, "b3": true}))
+}
+
+func newCountingBroker(t *testing.T, status int, calls *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, err := w.Write([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["LONG"],"columnNames":["cnt"]},"rows":[[1]]},"exceptions":[]}`))
+			assert.NoError(t, err)
+		}
+	}))
+}
+
+func TestRetryOnAnotherBroker(t *testing.T) {
+	var failingCalls, healthyCalls atomic.Int32
+	failing := newCountingBroker(t, http.StatusServiceUnavailable, &failingCalls)
+	defer failing.Close()
+	healthy := newCountingBroker(t, http.StatusOK, &healthyCalls)
+	defer healthy.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{failing.URL, healthy.URL},
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:        2,
+			InitialBackoff:     time.Millisecond,
+			AvoidFailedBrokers: true,
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		resp, err := conn.ExecuteSQL("", "select count(*) from baseballStats")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), resp.ResultTable.GetLong(0, 0))
+	}
+	assert.Equal(t, int32(10), healthyCalls.Load())
+	// Each query hit the failing broker at most once.
+	assert.LessOrEqual(t, failingCalls.Load(), int32(10))
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	failing := newCountingBroker(t, http.StatusBadGateway, &calls)
+	defer failing.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{failing.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, AvoidFailedBrokers: true},
+	})
+	require.NoError(t, err)
+
+	_, err = conn.ExecuteSQL("", "select 1")
+	var httpErr *BrokerHTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	badRequest := newCountingBroker(t, http.StatusBadRequest, &calls)
+	defer badRequest.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{badRequest.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQL("", "select 1")
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryOnConnectionRefused(t *testing.T) {
+	var healthyCalls atomic.Int32
+	healthy := newCountingBroker(t, http.StatusOK, &healthyCalls)
+	defer healthy.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{downURL, healthy.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, AvoidFailedBrokers: true},
+	})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = conn.ExecuteSQL("", "select 1")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(5), healthyCalls.Load())
+}
+
+func TestRetryOnConnectionClosed(t *testing.T) {
+	var calls atomic.Int32
+	broker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Close the connection without answering, like a broker shutting down.
+			conn, _, err := http.NewResponseController(w).Hijack()
+			require.NoError(t, err)
+			assert.NoError(t, conn.Close())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer broker.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{broker.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQL("", "select 1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRetryBackoffHonorsContext(t *testing.T) {
+	var calls atomic.Int32
+	failing := newCountingBroker(t, http.StatusServiceUnavailable, &calls)
+	defer failing.Close()
+
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList:  []string{failing.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = conn.ExecuteSQLContext(ctx, "", "select 1")
+	assert.Less(t, time.Since(start), 10*time.Second)
+	var httpErr *BrokerHTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, int32(1), calls.Load())
+}
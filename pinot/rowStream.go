@@ -0,0 +1,178 @@
+package pinot
+
+import (
+	"context"
+	"io"
+	"iter"
+	"sync"
+)
+
+// streamingTransport is implemented by transports able to stream query results block by block.
+type streamingTransport interface {
+	stream(ctx context.Context, brokerAddress string, query *Request) (*RowStream, error)
+}
+
+// RowStream iterates over the rows of a query result as they are received from the broker,
+// as returned by Connection.QueryStream. With the gRPC transport, one response block is decoded
+// at a time and the next block is only received once the rows of the current one were consumed.
+//
+// A RowStream is not safe for concurrent use. It must be closed once done with it:
+//
+//	stream, err := conn.QueryStream(ctx, "baseballStats", "select playerName from baseballStats")
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		row := stream.Row()
+//		...
+//	}
+//	return stream.Err()
+type RowStream struct {
+	response  *BrokerResponse
+	nextBlock func() ([][]interface{}, error)
+	closeFn   func() error
+	onDone    func(err error)
+	// clientQueryID identifies the query for Connection.Cancel
+	clientQueryID string
+
+	rows      [][]interface{}
+	pos       int
+	err       error
+	done      bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newRowStream returns a stream over the blocks returned by nextBlock until it returns io.EOF.
+// closeFn releases the resources of the stream, such as the underlying RPC.
+func newRowStream(response *BrokerResponse, nextBlock func() ([][]interface{}, error), closeFn func() error) *RowStream {
+	return &RowStream{
+		response:  response,
+		nextBlock: nextBlock,
+		closeFn:   closeFn,
+		pos:       -1,
+	}
+}
+
+// newBufferedRowStream returns a stream over the rows of a fully received response,
+// for transports that do not stream results.
+func newBufferedRowStream(response *BrokerResponse) *RowStream {
+	var rows [][]interface{}
+	if response.ResultTable != nil {
+		rows = response.ResultTable.Rows
+		// The rows are exposed through the stream only, like for streamed responses.
+		table := *response.ResultTable
+		table.Rows = [][]interface{}{}
+		streamed := *response
+		streamed.ResultTable = &table
+		response = &streamed
+	}
+	sent := false
+	return newRowStream(response, func() ([][]interface{}, error) {
+		if sent {
+			return nil, io.EOF
+		}
+		sent = true
+		return rows, nil
+	}, func() error { return nil })
+}
+
+// ClientQueryID returns the client query ID of the query, to cancel it with Connection.Cancel.
+func (s *RowStream) ClientQueryID() string {
+	return s.clientQueryID
+}
+
+// Metadata returns the broker response received before the rows: query statistics and exceptions
+// are set, and its ResultTable, if any, holds the schema of the result but no rows.
+func (s *RowStream) Metadata() *BrokerResponse {
+	return s.response
+}
+
+// Schema returns the schema of the result, empty when the query returned no result table.
+func (s *RowStream) Schema() RespSchema {
+	if s.response.ResultTable == nil {
+		return RespSchema{}
+	}
+	return s.response.ResultTable.DataSchema
+}
+
+// Next advances the stream to the next row, receiving the next block from the broker when needed.
+// It returns false once all rows were read, an error occurred, or the stream was closed.
+func (s *RowStream) Next() bool {
+	if s.done {
+		return false
+	}
+	s.pos++
+	for s.pos >= len(s.rows) {
+		rows, err := s.nextBlock()
+		if err == io.EOF {
+			s.finish(nil)
+			return false
+		}
+		if err != nil {
+			s.err = err
+			s.finish(err)
+			return false
+		}
+		s.rows = rows
+		s.pos = 0
+	}
+	return true
+}
+
+// Row returns the current row. It is only valid after a call to Next returned true,
+// and until the next call to Next.
+func (s *RowStream) Row() []interface{} {
+	if s.pos < 0 || s.pos >= len(s.rows) {
+		return nil
+	}
+	return s.rows[s.pos]
+}
+
+// Err returns the error that ended the iteration, nil when all rows were read.
+func (s *RowStream) Err() error {
+	return s.err
+}
+
+// Close stops the stream, cancelling the RPC when rows are left unread. It is safe to call more than once.
+func (s *RowStream) Close() error {
+	s.finish(nil)
+	return s.closeErr
+}
+
+// All returns an iterator over the remaining rows of the stream, closing the stream once done.
+// An error ending the iteration is yielded with a nil row.
+func (s *RowStream) All() iter.Seq2[[]interface{}, error] {
+	return func(yield func([]interface{}, error) bool) {
+		defer s.Close()
+		for s.Next() {
+			if !yield(s.Row(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func (s *RowStream) setOnDone(onDone func(err error)) {
+	s.onDone = onDone
+}
+
+func (s *RowStream) close() error {
+	return s.Close()
+}
+
+// finish releases the stream once, reporting its outcome to onDone.
+func (s *RowStream) finish(err error) {
+	s.done = true
+	s.rows = nil
+	s.closeOnce.Do(func() {
+		s.closeErr = s.closeFn()
+		if s.onDone != nil {
+			s.onDone(err)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	proto "github.com/startreedata/pinot-client-go/pinot/proto"
+)
+
+func jsonRowBlock(t *testing.T, rows [][]interface{}) *proto.BrokerResponse {
+	return &proto.BrokerResponse{
+		Payload:  encodeJSONRowBlock(t, rows),
+		Metadata: map[string]string{"encoding": "JSON", "compression": "NONE", "rowSize": strconv.Itoa(len(rows))},
+	}
+}
+
+func newGrpcTestConnection(t *testing.T, address string) *Connection {
+	conn, err := NewWithConfig(&ClientConfig{
+		BrokerList: []string{address},
+		GrpcConfig: &GrpcConfig{Encoding: "JSON", Compression: "NONE"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestQueryStreamGrpc(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[],"numDocsScanned":3,"timeUsedMs":5}`)},
+		{Payload: encodeTestSchema(t, []string{"id", "name"}, []string{"LONG", "STRING"})},
+		jsonRowBlock(t, [][]interface{}{{json.Number("1"), "a"}, {json.Number("2"), "b"}}),
+		jsonRowBlock(t, [][]interface{}{}),
+		jsonRowBlock(t, [][]interface{}{{json.Number("3"), "c"}}),
+	})
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	stream, err := conn.QueryStream(context.Background(), "", "select id, name from t")
+	require.NoError(t, err)
+	assert.Equal(t, RespSchema{ColumnNames: []string{"id", "name"}, ColumnDataTypes: []string{"LONG", "STRING"}}, stream.Schema())
+	assert.Equal(t, int64(3), stream.Metadata().NumDocsScanned)
+	assert.Equal(t, 5, stream.Metadata().TimeUsedMs)
+	assert.Empty(t, stream.Metadata().ResultTable.Rows)
+	assert.Equal(t, map[string]int{"id": 0, "name": 1}, stream.Metadata().ResultTable.columnIndexes)
+	assert.Nil(t, stream.Row())
+
+	var names []string
+	for stream.Next() {
+		names = append(names, stream.Row()[1].(string))
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+	assert.False(t, stream.Next())
+	require.NoError(t, stream.Close())
+	require.NoError(t, stream.Close())
+}
+
+func TestQueryStreamAll(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[]}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		jsonRowBlock(t, [][]interface{}{{json.Number("1")}, {json.Number("2")}, {json.Number("3")}}),
+	})
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	stream, err := conn.QueryStream(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	var ids []interface{}
+	for row, err := range stream.All() {
+		require.NoError(t, err)
+		ids = append(ids, row[0])
+		if len(ids) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []interface{}{json.Number("1"), json.Number("2")}, ids)
+	// Breaking out of the loop closes the stream.
+	assert.False(t, stream.Next())
+}
+
+func TestQueryStreamDecodeError(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[]}`)},
+		{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		jsonRowBlock(t, [][]interface{}{{json.Number("1")}}),
+		{Payload: []byte("x"), Metadata: map[string]string{"encoding": "BAD", "compression": "NONE", "rowSize": "1"}},
+	})
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	stream, err := conn.QueryStream(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	var rows int
+	var iterErr error
+	for _, err := range stream.All() {
+		if err != nil {
+			iterErr = err
+			continue
+		}
+		rows++
+	}
+	assert.Equal(t, 1, rows)
+	assert.ErrorContains(t, iterErr, "unsupported grpc encoding")
+	assert.Equal(t, iterErr, stream.Err())
+}
+
+// endlessPinotQueryBrokerServer streams row blocks until the client cancels the RPC.
+type endlessPinotQueryBrokerServer struct {
+	proto.UnimplementedPinotQueryBrokerServer
+	header   []*proto.BrokerResponse
+	block    *proto.BrokerResponse
+	finished chan error
+}
+
+func (s *endlessPinotQueryBrokerServer) Submit(_ *proto.BrokerRequest, stream proto.PinotQueryBroker_SubmitServer) error {
+	for _, resp := range s.header {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	for {
+		if err := stream.Send(s.block); err != nil {
+			s.finished <- err
+			return err
+		}
+		if err := stream.Context().Err(); err != nil {
+			s.finished <- err
+			return err
+		}
+	}
+}
+
+func startEndlessGrpcTestServer(t *testing.T) (*grpc.Server, net.Listener, *endlessPinotQueryBrokerServer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	endless := &endlessPinotQueryBrokerServer{
+		header: []*proto.BrokerResponse{
+			{Payload: []byte(`{"exceptions":[]}`)},
+			{Payload: encodeTestSchema(t, []string{"id"}, []string{"LONG"})},
+		},
+		block:    jsonRowBlock(t, [][]interface{}{{json.Number("1")}}),
+		finished: make(chan error, 1),
+	}
+	proto.RegisterPinotQueryBrokerServer(server, endless)
+	go func() {
+		if serveErr := server.Serve(listener); serveErr != nil && !errors.Is(serveErr, grpc.ErrServerStopped) {
+			assert.NoError(t, serveErr)
+		}
+	}()
+	return server, listener, endless
+}
+
+func TestQueryStreamCloseCancelsRPC(t *testing.T) {
+	server, listener, endless := startEndlessGrpcTestServer(t)
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	stream, err := conn.QueryStream(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.True(t, stream.Next())
+	}
+	require.NoError(t, stream.Close())
+	select {
+	case err := <-endless.finished:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the RPC was not cancelled by Close")
+	}
+	assert.False(t, stream.Next())
+	assert.NoError(t, stream.Err())
+}
+
+func TestQueryStreamContextCancel(t *testing.T) {
+	server, listener, _ := startEndlessGrpcTestServer(t)
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := conn.QueryStream(ctx, "", "select id from t")
+	require.NoError(t, err)
+	defer stream.Close()
+	require.True(t, stream.Next())
+	cancel()
+	for stream.Next() {
+	}
+	assert.ErrorIs(t, stream.Err(), context.Canceled)
+}
+
+func TestQueryStreamHTTPFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["STRING"],"columnNames":["name"]},"rows":[["a"],["b"]]},"exceptions":[],"numDocsScanned":2}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+	stream, err := conn.QueryStream(context.Background(), "", "select name from t")
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, []string{"name"}, stream.Schema().ColumnNames)
+	assert.Equal(t, int64(2), stream.Metadata().NumDocsScanned)
+	assert.Empty(t, stream.Metadata().ResultTable.Rows)
+	var names []interface{}
+	for stream.Next() {
+		names = append(names, stream.Row()[0])
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []interface{}{"a", "b"}, names)
+}
+
+func TestQueryStreamExceptionsAsErrors(t *testing.T) {
+	server, listener, _ := startGrpcTestServer(t, []*proto.BrokerResponse{
+		{Payload: []byte(`{"exceptions":[{"errorCode":190,"message":"table missing"}]}`)},
+	})
+	defer server.Stop()
+	conn := newGrpcTestConnection(t, listener.Addr().String())
+
+	stream, err := conn.QueryStream(context.Background(), "", "select id from t")
+	require.NoError(t, err)
+	assert.Equal(t, RespSchema{}, stream.Schema())
+	assert.False(t, stream.Next())
+	assert.Len(t, stream.Metadata().Exceptions, 1)
+
+	_, err = conn.With(WithExceptionsAsErrors()).QueryStream(context.Background(), "", "select id from t")
+	assert.ErrorIs(t, err, ErrTableNotFound)
+}
+
+func TestQueryStreamClosedConnection(t *testing.T) {
+	conn, err := NewFromBrokerList([]string{"localhost:8000"})
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	_, err = conn.QueryStream(context.Background(), "", "select 1")
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+}
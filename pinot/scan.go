@@ -0,0 +1,544 @@
+package pinot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+	scannerType  = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// pinotTimestampLayouts are the layouts TIMESTAMP values are parsed with, the first one being the
+// format of Pinot JSON responses.
+var pinotTimestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Query executes an SQL query for a given table and scans the rows of its result into a slice of T,
+// as ResultTable.ScanInto does. Args replace the '?' placeholders of query, as with ExecuteSQLWithParams.
+// Pinot exceptions in the response are returned as errors, since the result may be partial.
+func Query[T any](ctx context.Context, conn *Connection, table string, query string, args ...interface{}) ([]T, error) {
+	if len(args) > 0 {
+		formatted, err := formatQuery(query, args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format query: %w", err)
+		}
+		query = formatted
+	}
+	resp, err := conn.ExecuteSQLContext(ctx, table, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+	results := []T{}
+	if resp.ResultTable == nil {
+		return results, nil
+	}
+	if err := resp.ResultTable.ScanInto(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ScanInto decodes the rows of the result table into dest, which must be a pointer to a slice of structs
+// or of pointers to structs. The slice is replaced by one element per row.
+//
+// Columns are mapped to the exported fields of the struct named by a `pinot:"column"` tag, or else to the
+// field whose name matches the column name case-insensitively. Fields tagged `pinot:"-"` are ignored,
+// and a column without a matching field is an error. Pointer fields are set to nil for null values,
+// slice fields receive multi-value columns, time.Time fields receive TIMESTAMP columns, and fields
+// implementing sql.Scanner scan the column value.
+//
+// When the slice element is not a struct, or is time.Time, *big.Float or *big.Int, the result must have a
+// single column, whose values are scanned into the elements.
+func (r ResultTable) ScanInto(dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Pointer || destValue.IsNil() || destValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan destination must be a non-nil pointer to a slice, got %T", dest)
+	}
+	sliceValue := destValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+
+	var plan []scanTarget
+	if structType.Kind() == reflect.Struct && !isScalarStruct(structType) {
+		var err error
+		if plan, err = r.structScanPlan(structType); err != nil {
+			return err
+		}
+	} else {
+		if r.GetColumnCount() != 1 {
+			return fmt.Errorf("cannot scan %d columns into %s, a single column is required", r.GetColumnCount(), elemType)
+		}
+		plan = []scanTarget{{column: 0, name: "value", fieldType: structType}}
+	}
+
+	rows := reflect.MakeSlice(sliceValue.Type(), len(r.Rows), len(r.Rows))
+	for rowIndex, row := range r.Rows {
+		target := rows.Index(rowIndex)
+		if elemType.Kind() == reflect.Pointer {
+			target.Set(reflect.New(structType))
+			target = target.Elem()
+		}
+		for _, field := range plan {
+			if field.column >= len(row) {
+				return fmt.Errorf("row %d has %d values, expected at least %d", rowIndex, len(row), field.column+1)
+			}
+			fieldValue := target
+			if field.index != nil {
+				fieldValue = target.FieldByIndex(field.index)
+			}
+			columnType := ""
+			if field.column < len(r.DataSchema.ColumnDataTypes) {
+				columnType = r.DataSchema.ColumnDataTypes[field.column]
+			}
+			if err := scanValue(row[field.column], columnType, fieldValue); err != nil {
+				return fmt.Errorf("row %d: cannot scan column %q (%s) into %s of type %s: %w",
+					rowIndex, r.columnName(field.column), columnType, field.name, field.fieldType, err)
+			}
+		}
+	}
+	sliceValue.Set(rows)
+	return nil
+}
+
+// scanTarget is where the values of a column are scanned into.
+type scanTarget struct {
+	column    int
+	index     []int
+	name      string
+	fieldType reflect.Type
+}
+
+func (r ResultTable) columnName(columnIndex int) string {
+	if columnIndex < len(r.DataSchema.ColumnNames) {
+		return r.DataSchema.ColumnNames[columnIndex]
+	}
+	return strconv.Itoa(columnIndex)
+}
+
+// structScanPlan maps every column of the result table to a field of structType.
+func (r ResultTable) structScanPlan(structType reflect.Type) ([]scanTarget, error) {
+	tagged := map[string]reflect.StructField{}
+	var untagged []reflect.StructField
+	for _, field := range reflect.VisibleFields(structType) {
+		if !field.IsExported() || throughEmbeddedPointer(structType, field.Index) {
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("pinot")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !hasTag {
+			// The fields of embedded structs are mapped instead.
+			continue
+		}
+		if name != "" {
+			tagged[name] = field
+		} else {
+			untagged = append(untagged, field)
+		}
+	}
+
+	plan := make([]scanTarget, 0, r.GetColumnCount())
+	for columnIndex, column := range r.DataSchema.ColumnNames {
+		field, ok := tagged[column]
+		if !ok {
+			for _, candidate := range untagged {
+				if strings.EqualFold(candidate.Name, column) {
+					field, ok = candidate, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("column %q has no matching field in %s", column, structType)
+		}
+		plan = append(plan, scanTarget{
+			column:    columnIndex,
+			index:     field.Index,
+			name:      structType.Name() + "." + field.Name,
+			fieldType: field.Type,
+		})
+	}
+	return plan, nil
+}
+
+// throughEmbeddedPointer reports whether the field at index is promoted through an embedded pointer,
+// which would have to be allocated to be set.
+func throughEmbeddedPointer(structType reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		field := structType.Field(i)
+		if field.Type.Kind() == reflect.Pointer {
+			return true
+		}
+		structType = field.Type
+	}
+	return false
+}
+
+func isScalarStruct(t reflect.Type) bool {
+	return t == timeType || t == bigFloatType || t == bigIntType || t == bigRatType || reflect.PointerTo(t).Implements(scannerType)
+}
+
+// scanValue stores a value of a Pinot column of the given type into target.
+func scanValue(value interface{}, columnType string, target reflect.Value) error {
+	if target.CanAddr() && target.Addr().Type().Implements(scannerType) {
+		return target.Addr().Interface().(sql.Scanner).Scan(driverValue(value))
+	}
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		default:
+			return fmt.Errorf("null value, use a pointer field for nullable columns")
+		}
+	}
+
+	switch target.Type() {
+	case timeType:
+		t, err := toTime(value)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(t))
+		return nil
+	case bigFloatType:
+		if rat, ok := value.(*big.Rat); ok {
+			target.Set(reflect.ValueOf(new(big.Float).SetRat(rat)).Elem())
+			return nil
+		}
+		f, _, err := big.ParseFloat(fmt.Sprint(value), 10, 0, big.ToNearestEven)
+		if err != nil {
+			return fmt.Errorf("invalid decimal %v", value)
+		}
+		target.Set(reflect.ValueOf(f).Elem())
+		return nil
+	case bigIntType:
+		if rat, ok := value.(*big.Rat); ok && rat.IsInt() {
+			target.Set(reflect.ValueOf(new(big.Int).Set(rat.Num())).Elem())
+			return nil
+		}
+		i, ok := new(big.Int).SetString(fmt.Sprint(value), 10)
+		if !ok {
+			return fmt.Errorf("invalid integer %v", value)
+		}
+		target.Set(reflect.ValueOf(i).Elem())
+		return nil
+	case bigRatType:
+		rat, err := toBigRat(value)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(rat).Elem())
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(target.Type().Elem())
+		if err := scanValue(value, columnType, elem.Elem()); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	case reflect.Interface:
+		if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			return fmt.Errorf("unexpected value type %T", value)
+		}
+		target.Set(reflect.ValueOf(value))
+		return nil
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			target.SetString(v)
+		case json.Number:
+			target.SetString(v.String())
+		case bool:
+			target.SetString(strconv.FormatBool(v))
+		case time.Time:
+			target.SetString(formatPinotTimestamp(v))
+		case *big.Rat:
+			target.SetString(formatBigRat(v))
+		default:
+			return fmt.Errorf("unexpected value type %T", value)
+		}
+		return nil
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			target.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			target.SetBool(b)
+		default:
+			return fmt.Errorf("unexpected value type %T", value)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if target.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, target.Type())
+		}
+		target.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if i < 0 || target.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows %s", i, target.Type())
+		}
+		target.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(value)
+		if err != nil {
+			return err
+		}
+		if target.OverflowFloat(f) {
+			return fmt.Errorf("value %v overflows %s", f, target.Type())
+		}
+		target.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		return scanSlice(value, columnType, target)
+	case reflect.Map:
+		valueOf := reflect.ValueOf(value)
+		if !valueOf.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("unexpected value type %T", value)
+		}
+		target.Set(valueOf)
+		return nil
+	default:
+		return fmt.Errorf("unsupported field type")
+	}
+}
+
+// scanSlice stores a multi-value column, or a BYTES value into a []byte, into target.
+func scanSlice(value interface{}, columnType string, target reflect.Value) error {
+	if target.Type().Elem().Kind() == reflect.Uint8 {
+		switch v := value.(type) {
+		case []byte:
+			target.SetBytes(append([]byte(nil), v...))
+			return nil
+		case string:
+			decoded, err := hex.DecodeString(v)
+			if err != nil {
+				return fmt.Errorf("invalid hex bytes: %w", err)
+			}
+			target.SetBytes(decoded)
+			return nil
+		}
+	}
+	values := reflect.ValueOf(value)
+	if values.Kind() != reflect.Slice {
+		return fmt.Errorf("unexpected value type %T for a slice", value)
+	}
+	elemType := strings.TrimSuffix(strings.ToUpper(columnType), "_ARRAY")
+	slice := reflect.MakeSlice(target.Type(), values.Len(), values.Len())
+	for i := 0; i < values.Len(); i++ {
+		if err := scanValue(values.Index(i).Interface(), elemType, slice.Index(i)); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	target.Set(slice)
+	return nil
+}
+
+func toInt64(value interface{}) (int64, error) {
+	var number string
+	switch v := value.(type) {
+	case json.Number:
+		number = v.String()
+	case string:
+		number = v
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > math.MaxInt64 {
+				return 0, fmt.Errorf("value %d overflows int64", rv.Uint())
+			}
+			return int64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return floatToInt64(rv.Float())
+		}
+		return 0, fmt.Errorf("unexpected value type %T", value)
+	}
+	i, err := strconv.ParseInt(number, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if isRangeError(err) {
+		return 0, err
+	}
+	// Whole numbers may be returned as "42.0".
+	f, floatErr := strconv.ParseFloat(number, 64)
+	if floatErr != nil {
+		return 0, err
+	}
+	return floatToInt64(f)
+}
+
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) || !isWithinInt64Range(f) {
+		return 0, fmt.Errorf("value %v is not an integer", f)
+	}
+	return int64(f), nil
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	}
+	return 0, fmt.Errorf("unexpected value type %T", value)
+}
+
+// toTime converts a TIMESTAMP value, formatted as in Pinot responses or in epoch milliseconds, to a time in UTC.
+func toTime(value interface{}) (time.Time, error) {
+	return toTimeIn(value, time.UTC)
+}
+
+// toTimeIn converts a TIMESTAMP value to a time in loc, reading timestamps without a time zone in loc.
+func toTimeIn(value interface{}, loc *time.Location) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.In(loc), nil
+	case string:
+		return parsePinotTimestampIn(v, loc)
+	}
+	millis, err := toInt64(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis).In(loc), nil
+}
+
+// parsePinotTimestamp parses a TIMESTAMP value of a Pinot response, in UTC unless it has a time zone.
+func parsePinotTimestamp(value string) (time.Time, error) {
+	return parsePinotTimestampIn(value, time.UTC)
+}
+
+// parsePinotTimestampIn parses a TIMESTAMP value of a Pinot response, in loc unless it has a time zone.
+func parsePinotTimestampIn(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range pinotTimestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.In(loc), nil
+		}
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis).In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// formatPinotTimestamp formats a time like java.sql.Timestamp, as Pinot does in JSON responses:
+// yyyy-MM-dd HH:mm:ss.S with the trailing zeros of the fraction trimmed.
+func formatPinotTimestamp(t time.Time) string {
+	fraction := strings.TrimRight(fmt.Sprintf("%09d", t.Nanosecond()), "0")
+	if fraction == "" {
+		fraction = "0"
+	}
+	return t.Format("2006-01-02 15:04:05") + "." + fraction
+}
+
+// toBigRat converts a BIG_DECIMAL value, a decimal string or an exact rational number, to a new rational number.
+func toBigRat(value interface{}) (*big.Rat, error) {
+	switch v := value.(type) {
+	case *big.Rat:
+		return new(big.Rat).Set(v), nil
+	case string, json.Number:
+		if rat, ok := new(big.Rat).SetString(fmt.Sprint(v)); ok {
+			return rat, nil
+		}
+		return nil, fmt.Errorf("invalid decimal %v", value)
+	}
+	return nil, fmt.Errorf("unexpected value type %T", value)
+}
+
+// formatBigRat formats a decimal number without exponent, like BigDecimal.toPlainString but without
+// trailing zeros, since rational numbers do not keep the scale.
+func formatBigRat(r *big.Rat) string {
+	// The denominator of a decimal number is 2^a * 5^b, which needs max(a, b) digits after the point.
+	denom := new(big.Int).Set(r.Denom())
+	twos := denom.TrailingZeroBits()
+	denom.Rsh(denom, twos)
+	fives := 0
+	five := big.NewInt(5)
+	for quotient, remainder := new(big.Int), new(big.Int); denom.BitLen() > 1; fives++ {
+		quotient.QuoRem(denom, five, remainder)
+		if remainder.Sign() != 0 {
+			// Not a decimal number: round it to the 34 digits of a DECIMAL128.
+			return r.FloatString(34)
+		}
+		denom.Set(quotient)
+	}
+	return r.FloatString(max(int(twos), fives))
+}
+
+// driverValue converts a value of a Pinot response to a value accepted by sql.Scanner implementations.
+func driverValue(value interface{}) interface{} {
+	if rat, ok := value.(*big.Rat); ok {
+		return formatBigRat(rat)
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	if f, err := number.Float64(); err == nil {
+		return f
+	}
+	return number.String()
+}
@@ -0,0 +1,240 @@
+package pinot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scanBase struct {
+	ID int64
+}
+
+type scanPlayer struct {
+	scanBase
+	Name    string    `pinot:"playerName"`
+	Runs    int32     `pinot:"homeRuns"`
+	Average *float64  `pinot:"avg"`
+	Teams   []string  `pinot:"teams"`
+	Scores  []int     `pinot:"scores"`
+	Joined  time.Time `pinot:"joined"`
+	Active  bool
+	Ignored string `pinot:"-"`
+}
+
+func scanTestTable() ResultTable {
+	return ResultTable{
+		DataSchema: RespSchema{
+			ColumnNames:     []string{"id", "playerName", "homeRuns", "avg", "teams", "scores", "joined", "ACTIVE"},
+			ColumnDataTypes: []string{"LONG", "STRING", "INT", "DOUBLE", "STRING_ARRAY", "INT_ARRAY", "TIMESTAMP", "BOOLEAN"},
+		},
+		Rows: [][]interface{}{
+			{json.Number("1"), "Babe", json.Number("714"), json.Number("0.342"), []interface{}{"NYY", "BOS"},
+				[]interface{}{json.Number("1"), json.Number("2")}, "1914-07-11 00:00:00.0", true},
+			// Arrow-decoded multi-value columns come as typed slices.
+			{json.Number("2"), "Hank", json.Number("755.0"), nil, []string{"ATL"}, []int{3}, "1954-04-13 12:30:00.5", false},
+		},
+	}
+}
+
+func TestScanIntoStructs(t *testing.T) {
+	var players []scanPlayer
+	require.NoError(t, scanTestTable().ScanInto(&players))
+	require.Len(t, players, 2)
+
+	assert.Equal(t, int64(1), players[0].ID)
+	assert.Equal(t, "Babe", players[0].Name)
+	assert.Equal(t, int32(714), players[0].Runs)
+	require.NotNil(t, players[0].Average)
+	assert.Equal(t, 0.342, *players[0].Average)
+	assert.Equal(t, []string{"NYY", "BOS"}, players[0].Teams)
+	assert.Equal(t, []int{1, 2}, players[0].Scores)
+	assert.Equal(t, time.Date(1914, 7, 11, 0, 0, 0, 0, time.UTC), players[0].Joined)
+	assert.True(t, players[0].Active)
+
+	assert.Equal(t, int32(755), players[1].Runs)
+	assert.Nil(t, players[1].Average)
+	assert.Equal(t, []string{"ATL"}, players[1].Teams)
+	assert.Equal(t, []int{3}, players[1].Scores)
+	assert.Equal(t, time.Date(1954, 4, 13, 12, 30, 0, 500000000, time.UTC), players[1].Joined)
+
+	var pointers []*scanPlayer
+	require.NoError(t, scanTestTable().ScanInto(&pointers))
+	require.Len(t, pointers, 2)
+	assert.Equal(t, "Hank", pointers[1].Name)
+}
+
+func TestScanIntoSingleColumn(t *testing.T) {
+	table := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"count(*)"}, ColumnDataTypes: []string{"LONG"}},
+		Rows:       [][]interface{}{{json.Number("97889")}},
+	}
+	var counts []int64
+	require.NoError(t, table.ScanInto(&counts))
+	assert.Equal(t, []int64{97889}, counts)
+
+	var nullable []sql.NullInt64
+	require.NoError(t, table.ScanInto(&nullable))
+	assert.Equal(t, []sql.NullInt64{{Int64: 97889, Valid: true}}, nullable)
+
+	var decimals []*big.Float
+	require.NoError(t, table.ScanInto(&decimals))
+	require.Len(t, decimals, 1)
+	assert.Equal(t, "97889", decimals[0].Text('f', 0))
+
+	var times []time.Time
+	require.NoError(t, table.ScanInto(&times))
+	assert.Equal(t, time.UnixMilli(97889).UTC(), times[0])
+
+	var tooMany []int64
+	err := scanTestTable().ScanInto(&tooMany)
+	assert.ErrorContains(t, err, "cannot scan 8 columns into int64")
+}
+
+func TestScanIntoBytes(t *testing.T) {
+	table := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"raw"}, ColumnDataTypes: []string{"BYTES"}},
+		Rows:       [][]interface{}{{"0a0b"}},
+	}
+	var values []struct{ Raw []byte }
+	require.NoError(t, table.ScanInto(&values))
+	assert.Equal(t, []byte{0x0a, 0x0b}, values[0].Raw)
+}
+
+func TestScanIntoArrowValues(t *testing.T) {
+	joined := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	table := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"joined", "salary"}, ColumnDataTypes: []string{"TIMESTAMP", "BIG_DECIMAL"}},
+		Rows:       [][]interface{}{{joined, big.NewRat(2501, 20)}},
+	}
+	var typed []struct {
+		Joined time.Time
+		Salary *big.Rat
+	}
+	require.NoError(t, table.ScanInto(&typed))
+	assert.Equal(t, joined, typed[0].Joined)
+	assert.Equal(t, big.NewRat(2501, 20), typed[0].Salary)
+
+	var formatted []struct {
+		Joined string
+		Salary string
+	}
+	require.NoError(t, table.ScanInto(&formatted))
+	assert.Equal(t, "2024-01-02 03:04:05.0", formatted[0].Joined)
+	assert.Equal(t, "125.05", formatted[0].Salary)
+
+	var floats []struct {
+		Joined *time.Time
+		Salary float64
+	}
+	require.NoError(t, table.ScanInto(&floats))
+	assert.Equal(t, 125.05, floats[0].Salary)
+
+	var bigFloats []struct {
+		Joined time.Time
+		Salary big.Float
+	}
+	require.NoError(t, table.ScanInto(&bigFloats))
+	assert.Equal(t, "125.05", bigFloats[0].Salary.Text('f', 2))
+}
+
+func TestScanIntoErrors(t *testing.T) {
+	table := scanTestTable()
+	var players []scanPlayer
+	assert.ErrorContains(t, table.ScanInto(players), "must be a non-nil pointer to a slice")
+	assert.ErrorContains(t, table.ScanInto(&table), "must be a non-nil pointer to a slice")
+
+	var partial []struct {
+		ID   int64
+		Name string `pinot:"playerName"`
+	}
+	assert.EqualError(t, table.ScanInto(&partial), `column "homeRuns" has no matching field in struct { ID int64; Name string "pinot:\"playerName\"" }`)
+
+	type narrow struct {
+		Value int8
+	}
+	overflow := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"value"}, ColumnDataTypes: []string{"INT"}},
+		Rows:       [][]interface{}{{json.Number("1")}, {json.Number("300")}},
+	}
+	var narrows []narrow
+	assert.EqualError(t, overflow.ScanInto(&narrows),
+		`row 1: cannot scan column "value" (INT) into narrow.Value of type int8: value 300 overflows int8`)
+
+	null := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"value"}, ColumnDataTypes: []string{"INT"}},
+		Rows:       [][]interface{}{{nil}},
+	}
+	assert.ErrorContains(t, null.ScanInto(&narrows), "null value, use a pointer field for nullable columns")
+
+	mismatch := ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"value"}, ColumnDataTypes: []string{"STRING"}},
+		Rows:       [][]interface{}{{"abc"}},
+	}
+	assert.ErrorContains(t, mismatch.ScanInto(&narrows), `cannot scan column "value" (STRING) into narrow.Value of type int8`)
+	var floats []float64
+	assert.Error(t, ResultTable{
+		DataSchema: RespSchema{ColumnNames: []string{"value"}, ColumnDataTypes: []string{"DOUBLE"}},
+		Rows:       [][]interface{}{{true}},
+	}.ScanInto(&floats))
+	var timestamps []time.Time
+	assert.ErrorContains(t, mismatch.ScanInto(&timestamps), `invalid timestamp "abc"`)
+}
+
+func TestParsePinotTimestamp(t *testing.T) {
+	for value, expected := range map[string]time.Time{
+		"2024-01-02 03:04:05.0":     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02 03:04:05":       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02T03:04:05+02:00": time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC),
+		"2024-01-02":                time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"1704164645000":             time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	} {
+		parsed, err := parsePinotTimestamp(value)
+		require.NoError(t, err, value)
+		assert.True(t, expected.Equal(parsed), value)
+	}
+}
+
+func TestQueryGeneric(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		queries = append(queries, body["sql"])
+		w.Header().Set("Content-Type", "application/json")
+		if len(queries) == 2 {
+			_, err := w.Write([]byte(`{"exceptions":[{"errorCode":190,"message":"TableDoesNotExistError"}]}`))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := w.Write([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["STRING","INT"],"columnNames":["playerName","homeRuns"]},"rows":[["Babe",714]]},"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	conn, err := NewFromBrokerList([]string{ts.URL})
+	require.NoError(t, err)
+
+	type player struct {
+		Name string `pinot:"playerName"`
+		Runs int    `pinot:"homeRuns"`
+	}
+	players, err := Query[player](context.Background(), conn, "baseballStats",
+		"select playerName, homeRuns from baseballStats where playerName = ? limit ?", "Babe", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []player{{Name: "Babe", Runs: 714}}, players)
+	assert.Equal(t, "select playerName, homeRuns from baseballStats where playerName = 'Babe' limit 1", queries[0])
+
+	_, err = Query[player](context.Background(), conn, "baseballStats", "select playerName, homeRuns from baseballStats")
+	assert.ErrorIs(t, err, ErrTableNotFound)
+
+	_, err = Query[player](context.Background(), conn, "baseballStats", "select ?", "a", "b")
+	assert.ErrorContains(t, err, "failed to format query")
+}
@@ -7,19 +7,35 @@ import (
 
 type simpleBrokerSelector struct {
 	brokerList []string
+	// observer is notified of the broker list once initialized, nil when not set
+	observer BrokerSelectorObserver
 }
 
 func (s *simpleBrokerSelector) init() error {
 	if len(s.brokerList) == 0 {
-		return fmt.Errorf("no pre-configured broker lists set in simpleBrokerSelector")
+		return fmt.Errorf("%w: no pre-configured broker lists set in simpleBrokerSelector", ErrNoBrokerAvailable)
+	}
+	if s.observer != nil {
+		s.observer.BrokersUpdated(nil, s.brokerList)
 	}
 	return nil
 }
 
 func (s *simpleBrokerSelector) selectBroker(_ string) (string, error) {
 	if len(s.brokerList) == 0 {
-		return "", fmt.Errorf("no pre-configured broker lists set in simpleBrokerSelector")
+		return "", fmt.Errorf("%w: no pre-configured broker lists set in simpleBrokerSelector", ErrNoBrokerAvailable)
 	}
 	// #nosec G404
 	return s.brokerList[rand.Intn(len(s.brokerList))], nil
 }
+
+func (s *simpleBrokerSelector) availableBrokers(_ string) ([]string, error) {
+	if len(s.brokerList) == 0 {
+		return nil, fmt.Errorf("%w: no pre-configured broker lists set in simpleBrokerSelector", ErrNoBrokerAvailable)
+	}
+	return s.brokerList, nil
+}
+
+func (s *simpleBrokerSelector) Close() error {
+	return nil
+}
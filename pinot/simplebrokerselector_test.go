@@ -33,10 +33,19 @@ func TestWithEmptyBrokerList(t *testing.T) {
 		brokerList: []string{},
 	}
 	err := s.init()
-	assert.EqualError(t, err, "no pre-configured broker lists set in simpleBrokerSelector")
+	assert.EqualError(t, err, "no available broker found: no pre-configured broker lists set in simpleBrokerSelector")
 	for i := 0; i < 10; i++ {
 		brokerName, err := s.selectBroker("t")
 		assert.Equal(t, "", brokerName)
-		assert.EqualError(t, err, "no pre-configured broker lists set in simpleBrokerSelector")
+		assert.EqualError(t, err, "no available broker found: no pre-configured broker lists set in simpleBrokerSelector")
+		assert.ErrorIs(t, err, ErrNoBrokerAvailable)
 	}
 }
+
+func TestSimpleBrokerSelectorObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	s := &simpleBrokerSelector{brokerList: []string{"broker0", "broker1"}, observer: observer}
+	assert.NoError(t, s.init())
+	assert.Equal(t, [][]string{{"broker0", "broker1"}}, observer.allBrokers)
+	assert.Equal(t, []map[string][]string{nil}, observer.tableBrokers)
+}
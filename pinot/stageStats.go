@@ -0,0 +1,62 @@
+package pinot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StageStats is a node of the operator tree of a multi-stage engine query, as returned in
+// BrokerResponse.StageStats. MAILBOX_SEND nodes start a new stage, run by Parallelism workers.
+type StageStats struct {
+	// Type is the type of the operator, such as MAILBOX_RECEIVE, HASH_JOIN, AGGREGATE or LEAF.
+	Type            string        `json:"type"`
+	Stage           int           `json:"stage,omitempty"`
+	Parallelism     int           `json:"parallelism,omitempty"`
+	Table           string        `json:"table,omitempty"`
+	ExecutionTimeMs int64         `json:"executionTimeMs"`
+	EmittedRows     int64         `json:"emittedRows"`
+	Children        []*StageStats `json:"children,omitempty"`
+	// Extra holds the operator specific statistics, such as fanIn for MAILBOX_RECEIVE operators
+	// or numDocsScanned for LEAF operators, nil when there is none.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the statistics of an operator, keeping the operator specific ones in Extra.
+func (s *StageStats) UnmarshalJSON(data []byte) error {
+	type stageStats StageStats
+	var decoded stageStats
+	extra, err := decodeJSONWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*s = StageStats(decoded)
+	s.Extra = extra
+	return nil
+}
+
+// String renders the operator tree as indented text, one operator per line, for instance:
+//
+//	MAILBOX_RECEIVE rows=10 time=12ms
+//	  MAILBOX_SEND stage=1 parallelism=2 rows=10 time=10ms
+//	    LEAF table=baseballStats rows=10 time=9ms
+func (s *StageStats) String() string {
+	if s == nil {
+		return ""
+	}
+	var b strings.Builder
+	writeTree(&b, s, 0, func(n *StageStats) (string, []*StageStats) {
+		line := n.Type
+		if n.Stage != 0 {
+			line += fmt.Sprintf(" stage=%d", n.Stage)
+		}
+		if n.Parallelism != 0 {
+			line += fmt.Sprintf(" parallelism=%d", n.Parallelism)
+		}
+		if n.Table != "" {
+			line += " table=" + n.Table
+		}
+		return line + fmt.Sprintf(" rows=%d time=%dms", n.EmittedRows, n.ExecutionTimeMs), n.Children
+	})
+	return b.String()
+}
@@ -0,0 +1,20 @@
+package pinot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageStatsString(t *testing.T) {
+	stats := &StageStats{Type: "MAILBOX_RECEIVE", EmittedRows: 10, ExecutionTimeMs: 12, Children: []*StageStats{{
+		Type: "MAILBOX_SEND", Stage: 1, Parallelism: 2, EmittedRows: 10, ExecutionTimeMs: 10, Children: []*StageStats{{
+			Type: "LEAF", Table: "baseballStats", EmittedRows: 10, ExecutionTimeMs: 9,
+		}},
+	}}}
+	assert.Equal(t, `MAILBOX_RECEIVE rows=10 time=12ms
+  MAILBOX_SEND stage=1 parallelism=2 rows=10 time=10ms
+    LEAF table=baseballStats rows=10 time=9ms
+`, stats.String())
+	assert.Equal(t, "", (*StageStats)(nil).String())
+}
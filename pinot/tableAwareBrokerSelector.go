@@ -16,9 +16,58 @@ type tableAwareBrokerSelector struct {
 	tableBrokerMap map[string]([]string)
 	allBrokerList  []string
 	rwMux          sync.RWMutex
+	done           chan struct{}
+	closeOnce      sync.Once
+	// observer is notified of the broker list refreshes, nil when not set
+	observer BrokerSelectorObserver
+	logger   Logger
+}
+
+// setBrokers replaces the broker lists with refreshed ones.
+func (s *tableAwareBrokerSelector) setBrokers(tableBrokerMap map[string][]string, allBrokerList []string) {
+	s.rwMux.Lock()
+	s.tableBrokerMap = tableBrokerMap
+	s.allBrokerList = allBrokerList
+	s.rwMux.Unlock()
+	if s.observer != nil {
+		s.observer.BrokersUpdated(tableBrokerMap, allBrokerList)
+	}
+}
+
+// refreshFailed reports a failed refresh of the broker lists to the observer.
+func (s *tableAwareBrokerSelector) refreshFailed(err error) {
+	if s.observer != nil {
+		s.observer.BrokersRefreshFailed(err)
+	}
+}
+
+// doneChan returns the channel closed by Close, which stops background broker refresh loops.
+func (s *tableAwareBrokerSelector) doneChan() chan struct{} {
+	s.rwMux.Lock()
+	defer s.rwMux.Unlock()
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+	return s.done
+}
+
+func (s *tableAwareBrokerSelector) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.doneChan())
+	})
+	return nil
 }
 
 func (s *tableAwareBrokerSelector) selectBroker(table string) (string, error) {
+	brokerList, err := s.availableBrokers(table)
+	if err != nil {
+		return "", err
+	}
+	// #nosec G404
+	return brokerList[rand.Intn(len(brokerList))], nil
+}
+
+func (s *tableAwareBrokerSelector) availableBrokers(table string) ([]string, error) {
 	tableName := extractTableName(table)
 	var brokerList []string
 	if tableName == "" {
@@ -26,7 +75,7 @@ func (s *tableAwareBrokerSelector) selectBroker(table string) (string, error) {
 		brokerList = s.allBrokerList
 		s.rwMux.RUnlock()
 		if len(brokerList) == 0 {
-			return "", fmt.Errorf("no available broker found")
+			return nil, ErrNoBrokerAvailable
 		}
 	} else {
 		var found bool
@@ -34,14 +83,13 @@ func (s *tableAwareBrokerSelector) selectBroker(table string) (string, error) {
 		brokerList, found = s.tableBrokerMap[tableName]
 		s.rwMux.RUnlock()
 		if !found {
-			return "", fmt.Errorf("unable to find the table: %s", table)
+			return nil, fmt.Errorf("%w: %s", ErrTableNotFound, table)
 		}
 		if len(brokerList) == 0 {
-			return "", fmt.Errorf("no available broker found for table: %s", table)
+			return nil, fmt.Errorf("%w for table: %s", ErrNoBrokerAvailable, table)
 		}
 	}
-	// #nosec G404
-	return brokerList[rand.Intn(len(brokerList))], nil
+	return brokerList, nil
 }
 
 func extractTableName(table string) string {
@@ -33,8 +33,12 @@ func TestErrorSelectBroker(t *testing.T) {
 	}
 	_, err := emptySelector.selectBroker("")
 	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrNoBrokerAvailable)
 	_, err = emptySelector.selectBroker("myTable")
 	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrNoBrokerAvailable)
 	_, err = emptySelector.selectBroker("unexistTable")
 	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	assert.EqualError(t, err, "unable to find the table: unexistTable")
 }
@@ -0,0 +1,231 @@
+package pinot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Trace is the decoded trace of a query executed with tracing enabled, see WithTrace.
+type Trace struct {
+	// Servers holds the trace of each server, sorted by server name.
+	Servers []*ServerTrace
+}
+
+// ServerTrace is the trace of a query on a server. Servers run the operators processing segments
+// in worker threads, each having its own ThreadTrace.
+type ServerTrace struct {
+	// Server is the name of the server, such as Server_127.0.0.1_7050 for an offline server,
+	// suffixed with _R for a realtime one.
+	Server string
+	// Operators holds the timings of the operators run on the request thread, such as the combine
+	// and instance response operators.
+	Operators []OperatorTiming
+	// Threads holds the traces of the worker threads started by the request thread, sorted by trace id.
+	Threads []*ThreadTrace
+	// Extra holds the entries of the request thread trace which are not operator timings.
+	Extra map[string]json.RawMessage
+}
+
+// ThreadTrace is the trace of a worker thread of a server. Trace ids encode the thread tree: "0_1" is the
+// second worker thread of request thread "0", and "0_1_0" the first thread started by "0_1".
+type ThreadTrace struct {
+	// ID is the trace id of the thread, such as "0_1".
+	ID string
+	// Segment is the name of the segment processed, when reported by the server.
+	Segment   string
+	Operators []OperatorTiming
+	// Children holds the traces of the threads started by this thread, sorted by trace id.
+	Children []*ThreadTrace
+	// Extra holds the entries of the trace which are not operator timings.
+	Extra map[string]json.RawMessage
+}
+
+// OperatorTiming is the execution time of an operator. The time of an operator includes the time of
+// the operators it pulls blocks from.
+type OperatorTiming struct {
+	Operator string
+	TimeMs   int64
+}
+
+// Trace decodes TraceInfo, returning nil when the response has no trace.
+func (r *BrokerResponse) Trace() (*Trace, error) {
+	if len(r.TraceInfo) == 0 {
+		return nil, nil
+	}
+	return ParseTraceInfo(r.TraceInfo)
+}
+
+// ParseTraceInfo decodes the trace info of a broker response. It holds, for each server, a JSON array of
+// thread traces, each mapping a trace id to a list of single entry objects, for instance:
+//
+//	[{"0":[{"SelectionOnlyCombineOperator Time":5},{"InstanceResponseOperator Time":6}]},
+//	 {"0_0":[{"FilterOperator Time":1},{"SelectionOnlyOperator Time":3}]}]
+//
+// Entries whose key ends with " Time" are operator timings.
+func ParseTraceInfo(traceInfo map[string]string) (*Trace, error) {
+	trace := &Trace{Servers: make([]*ServerTrace, 0, len(traceInfo))}
+	for server, value := range traceInfo {
+		serverTrace, err := parseServerTrace(server, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode trace of server %s: %w", server, err)
+		}
+		trace.Servers = append(trace.Servers, serverTrace)
+	}
+	sort.Slice(trace.Servers, func(i, j int) bool { return trace.Servers[i].Server < trace.Servers[j].Server })
+	return trace, nil
+}
+
+func parseServerTrace(server string, value string) (*ServerTrace, error) {
+	var entriesByThread []map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &entriesByThread); err != nil {
+		return nil, err
+	}
+	serverTrace := &ServerTrace{Server: server}
+	threads := make(map[string]*ThreadTrace)
+	for _, thread := range entriesByThread {
+		for id, entries := range thread {
+			threadTrace := &ThreadTrace{ID: id}
+			for _, entry := range entries {
+				for key, raw := range entry {
+					if err := threadTrace.addEntry(key, raw); err != nil {
+						return nil, fmt.Errorf("trace %s: %w", id, err)
+					}
+				}
+			}
+			if !strings.Contains(id, "_") {
+				// Trace ids of worker threads are suffixed with the index of the thread.
+				serverTrace.Operators = append(serverTrace.Operators, threadTrace.Operators...)
+				serverTrace.Extra = mergeRawMaps(serverTrace.Extra, threadTrace.Extra)
+				continue
+			}
+			threads[id] = threadTrace
+		}
+	}
+	for id, thread := range threads {
+		// Threads whose parent did not report a trace are attached to the request thread.
+		if parent, ok := threads[id[:strings.LastIndex(id, "_")]]; ok {
+			parent.Children = append(parent.Children, thread)
+			continue
+		}
+		serverTrace.Threads = append(serverTrace.Threads, thread)
+	}
+	sortThreads(serverTrace.Threads)
+	return serverTrace, nil
+}
+
+// sortThreads sorts threads and their children by trace id.
+func sortThreads(threads []*ThreadTrace) {
+	sort.Slice(threads, func(i, j int) bool { return threads[i].ID < threads[j].ID })
+	for _, thread := range threads {
+		sortThreads(thread.Children)
+	}
+}
+
+func (s *ThreadTrace) addEntry(key string, raw json.RawMessage) error {
+	if operator, ok := strings.CutSuffix(key, " Time"); ok {
+		var timeMs json.Number
+		if err := decodeJSONWithNumber(raw, &timeMs); err != nil {
+			return fmt.Errorf("invalid time of %s: %w", operator, err)
+		}
+		val, err := toInt64(timeMs)
+		if err != nil {
+			return fmt.Errorf("invalid time of %s: %w", operator, err)
+		}
+		s.Operators = append(s.Operators, OperatorTiming{Operator: operator, TimeMs: val})
+		return nil
+	}
+	if strings.EqualFold(key, "segment") || strings.EqualFold(key, "segmentName") {
+		if err := json.Unmarshal(raw, &s.Segment); err == nil {
+			return nil
+		}
+	}
+	s.Extra = mergeRawMaps(s.Extra, map[string]json.RawMessage{key: raw})
+	return nil
+}
+
+// walkThreads calls fn for each thread of the trees rooted at threads, parents first.
+func walkThreads(threads []*ThreadTrace, fn func(*ThreadTrace)) {
+	for _, thread := range threads {
+		fn(thread)
+		walkThreads(thread.Children, fn)
+	}
+}
+
+func mergeRawMaps(dst map[string]json.RawMessage, src map[string]json.RawMessage) map[string]json.RawMessage {
+	for key, value := range src {
+		if dst == nil {
+			dst = make(map[string]json.RawMessage, len(src))
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// TimeMs returns the time spent by the server on the query: the time of its slowest operator,
+// which includes the time of the operators nested in it.
+func (s *ServerTrace) TimeMs() int64 {
+	timeMs := maxOperatorTime(s.Operators)
+	walkThreads(s.Threads, func(thread *ThreadTrace) {
+		timeMs = max(timeMs, thread.TimeMs())
+	})
+	return timeMs
+}
+
+// TimeMs returns the time spent by the worker thread: the time of its slowest operator.
+// It does not include the time of its children, which run concurrently.
+func (s *ThreadTrace) TimeMs() int64 {
+	return maxOperatorTime(s.Operators)
+}
+
+func maxOperatorTime(operators []OperatorTiming) int64 {
+	var timeMs int64
+	for _, operator := range operators {
+		timeMs = max(timeMs, operator.TimeMs)
+	}
+	return timeMs
+}
+
+// TimeByOperator returns the total time of each operator over all servers and threads.
+// As operator times include the time of nested operators, the totals overlap.
+func (t *Trace) TimeByOperator() map[string]int64 {
+	totals := make(map[string]int64)
+	for _, server := range t.Servers {
+		for _, operator := range server.Operators {
+			totals[operator.Operator] += operator.TimeMs
+		}
+		walkThreads(server.Threads, func(thread *ThreadTrace) {
+			for _, operator := range thread.Operators {
+				totals[operator.Operator] += operator.TimeMs
+			}
+		})
+	}
+	return totals
+}
+
+// SlowestServer returns the server which spent the most time on the query, nil when there is none.
+func (t *Trace) SlowestServer() *ServerTrace {
+	var slowest *ServerTrace
+	for _, server := range t.Servers {
+		if slowest == nil || server.TimeMs() > slowest.TimeMs() {
+			slowest = server
+		}
+	}
+	return slowest
+}
+
+// SlowestThread returns the worker thread, at any depth of the thread trees, which spent the most time
+// on the query, along with its server, or nils when there is none.
+func (t *Trace) SlowestThread() (*ServerTrace, *ThreadTrace) {
+	var slowestServer *ServerTrace
+	var slowest *ThreadTrace
+	for _, server := range t.Servers {
+		walkThreads(server.Threads, func(thread *ThreadTrace) {
+			if slowest == nil || thread.TimeMs() > slowest.TimeMs() {
+				slowestServer, slowest = server, thread
+			}
+		})
+	}
+	return slowestServer, slowest
+}
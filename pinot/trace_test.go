@@ -0,0 +1,86 @@
+package pinot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerResponseTrace(t *testing.T) {
+	var resp BrokerResponse
+	require.NoError(t, decodeJSONWithNumber([]byte(`{"exceptions":[],"traceInfo":{
+		"Server_127.0.0.1_7050": "[{\"0\":[{\"SelectionOnlyCombineOperator Time\":5},{\"InstanceResponseOperator Time\":6},{\"numSegments\":2}]},{\"0_0\":[{\"segmentName\":\"t_0\"},{\"FilterOperator Time\":1},{\"SelectionOnlyOperator Time\":3}]},{\"0_1\":[{\"FilterOperator Time\":2},{\"SelectionOnlyOperator Time\":4}]}]",
+		"Server_127.0.0.1_7050_R": "[{\"0\":[{\"InstanceResponseOperator Time\":9}]},{\"0_0\":[{\"SelectionOnlyOperator Time\":8}]}]"
+	}}`), &resp))
+
+	trace, err := resp.Trace()
+	require.NoError(t, err)
+	require.Len(t, trace.Servers, 2)
+	offline := trace.Servers[0]
+	assert.Equal(t, "Server_127.0.0.1_7050", offline.Server)
+	assert.Equal(t, []OperatorTiming{{"SelectionOnlyCombineOperator", 5}, {"InstanceResponseOperator", 6}}, offline.Operators)
+	assert.Equal(t, map[string]json.RawMessage{"numSegments": json.RawMessage("2")}, offline.Extra)
+	require.Len(t, offline.Threads, 2)
+	assert.Equal(t, "0_0", offline.Threads[0].ID)
+	assert.Equal(t, "t_0", offline.Threads[0].Segment)
+	assert.Nil(t, offline.Threads[0].Extra)
+	assert.Nil(t, offline.Threads[0].Children)
+	assert.Equal(t, []OperatorTiming{{"FilterOperator", 2}, {"SelectionOnlyOperator", 4}}, offline.Threads[1].Operators)
+	assert.Equal(t, int64(6), offline.TimeMs())
+	assert.Equal(t, int64(4), offline.Threads[1].TimeMs())
+
+	assert.Equal(t, map[string]int64{
+		"SelectionOnlyCombineOperator": 5,
+		"InstanceResponseOperator":     15,
+		"FilterOperator":               3,
+		"SelectionOnlyOperator":        15,
+	}, trace.TimeByOperator())
+	assert.Equal(t, "Server_127.0.0.1_7050_R", trace.SlowestServer().Server)
+	server, thread := trace.SlowestThread()
+	assert.Equal(t, "Server_127.0.0.1_7050_R", server.Server)
+	assert.Equal(t, int64(8), thread.TimeMs())
+}
+
+func TestParseTraceInfoThreadTree(t *testing.T) {
+	trace, err := ParseTraceInfo(map[string]string{"Server_1": `[
+		{"0":[{"CombineOperator Time":9}]},
+		{"0_0":[{"CombineOperator Time":7}]},
+		{"0_0_1":[{"FilterOperator Time":2}]},
+		{"0_0_0":[{"FilterOperator Time":8}]},
+		{"0_1":[{"FilterOperator Time":1}]},
+		{"0_2_0":[{"FilterOperator Time":3}]}]`})
+	require.NoError(t, err)
+	server := trace.Servers[0]
+	require.Len(t, server.Threads, 3)
+	assert.Equal(t, "0_0", server.Threads[0].ID)
+	require.Len(t, server.Threads[0].Children, 2)
+	assert.Equal(t, "0_0_0", server.Threads[0].Children[0].ID)
+	assert.Equal(t, "0_0_1", server.Threads[0].Children[1].ID)
+	assert.Equal(t, "0_1", server.Threads[1].ID)
+	// The parent of 0_2_0 did not report a trace.
+	assert.Equal(t, "0_2_0", server.Threads[2].ID)
+
+	assert.Equal(t, int64(9), server.TimeMs())
+	assert.Equal(t, map[string]int64{"CombineOperator": 16, "FilterOperator": 14}, trace.TimeByOperator())
+	_, slowest := trace.SlowestThread()
+	assert.Equal(t, "0_0_0", slowest.ID)
+}
+
+func TestParseTraceInfoErrors(t *testing.T) {
+	trace, err := (&BrokerResponse{}).Trace()
+	require.NoError(t, err)
+	assert.Nil(t, trace)
+
+	empty := &Trace{}
+	assert.Nil(t, empty.SlowestServer())
+	server, thread := empty.SlowestThread()
+	assert.Nil(t, server)
+	assert.Nil(t, thread)
+
+	_, err = ParseTraceInfo(map[string]string{"Server_1": "{"})
+	assert.ErrorContains(t, err, "failed to decode trace of server Server_1")
+	_, err = ParseTraceInfo(map[string]string{"Server_1": `[{"0_0":[{"FilterOperator Time":"x"}]}]`})
+	assert.ErrorContains(t, err, "trace 0_0: invalid time of FilterOperator")
+}
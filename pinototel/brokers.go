@@ -0,0 +1,61 @@
+package pinototel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/startreedata/pinot-client-go/pinot"
+)
+
+// BrokersUpdated records the size of the broker lists, implementing pinot.BrokerSelectorObserver.
+func (i *Instrumentation) BrokersUpdated(tableBrokers map[string][]string, allBrokers []string) {
+	sizes := make(map[string]int, len(tableBrokers))
+	for table, brokers := range tableBrokers {
+		sizes[table] = len(brokers)
+	}
+	// Brokers serving several tables are listed once per table by Zookeeper discovery.
+	distinct := make(map[string]struct{}, len(allBrokers))
+	for _, broker := range allBrokers {
+		distinct[broker] = struct{}{}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tableBrokers = sizes
+	i.allBrokers = len(distinct)
+}
+
+// BrokersRefreshFailed counts a failed refresh of the broker lists, implementing pinot.BrokerSelectorObserver.
+func (i *Instrumentation) BrokersRefreshFailed(err error) {
+	i.refreshErrors.Add(context.Background(), 1, metric.WithAttributes(ErrorTypeKey.String(errorTypeOf(err))))
+}
+
+func (i *Instrumentation) observeBrokers(_ context.Context, observer metric.Int64Observer) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.tableBrokers == nil && i.allBrokers == 0 {
+		return nil
+	}
+	observer.Observe(int64(i.allBrokers))
+	for table, size := range i.tableBrokers {
+		observer.Observe(int64(size), metric.WithAttributes(TableKey.String(table)))
+	}
+	return nil
+}
+
+// chainedObserver forwards the broker lists and refresh failures to the observer set before Instrument,
+// then to the Instrumentation.
+type chainedObserver struct {
+	previous pinot.BrokerSelectorObserver
+	next     pinot.BrokerSelectorObserver
+}
+
+func (o chainedObserver) BrokersUpdated(tableBrokers map[string][]string, allBrokers []string) {
+	o.previous.BrokersUpdated(tableBrokers, allBrokers)
+	o.next.BrokersUpdated(tableBrokers, allBrokers)
+}
+
+func (o chainedObserver) BrokersRefreshFailed(err error) {
+	o.previous.BrokersRefreshFailed(err)
+	o.next.BrokersRefreshFailed(err)
+}
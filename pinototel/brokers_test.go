@@ -0,0 +1,81 @@
+package pinototel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/startreedata/pinot-client-go/pinot"
+)
+
+func TestBrokerSelectorMetrics(t *testing.T) {
+	providers := newTestProviders()
+	i, err := New(providers.options...)
+	require.NoError(t, err)
+	assert.NotContains(t, providers.collect(t), "pinot.client.broker_selector.brokers")
+
+	i.BrokersUpdated(map[string][]string{"a": {"b1:8000", "b2:8000"}, "b": {"b1:8000"}}, []string{"b1:8000", "b2:8000", "b1:8000"})
+	i.BrokersUpdated(map[string][]string{"a": {"b1:8000"}, "c": {}}, []string{"b1:8000"})
+	i.BrokersRefreshFailed(errors.New("zookeeper unavailable"))
+	i.BrokersRefreshFailed(errors.New("zookeeper unavailable"))
+
+	metrics := providers.collect(t)
+	sizes := make(map[string]int64)
+	for _, point := range metrics["pinot.client.broker_selector.brokers"].Data.(metricdata.Gauge[int64]).DataPoints {
+		table, _ := point.Attributes.Value(TableKey)
+		sizes[table.AsString()] = point.Value
+	}
+	// The gauge reports the latest broker lists only, the cluster wide one without a table.
+	assert.Equal(t, map[string]int64{"": 1, "a": 1, "c": 0}, sizes)
+
+	refreshErrors := metrics["pinot.client.broker_selector.refresh.errors"].Data.(metricdata.Sum[int64])
+	require.Len(t, refreshErrors.DataPoints, 1)
+	assert.Equal(t, int64(2), refreshErrors.DataPoints[0].Value)
+}
+
+func TestInstrument(t *testing.T) {
+	config := &pinot.ClientConfig{Interceptors: []pinot.Interceptor{func(next pinot.Transport) pinot.Transport { return next }}}
+	i, err := Instrument(config, newTestProviders().options...)
+	require.NoError(t, err)
+	assert.Len(t, config.Interceptors, 2)
+	assert.Len(t, config.QueryInterceptors, 1)
+	assert.Same(t, i, config.BrokerSelectorObserver)
+}
+
+type recordingObserver struct {
+	updates  int
+	failures []error
+}
+
+func (o *recordingObserver) BrokersUpdated(map[string][]string, []string) {
+	o.updates++
+}
+
+func (o *recordingObserver) BrokersRefreshFailed(err error) {
+	o.failures = append(o.failures, err)
+}
+
+func TestInstrumentChainsObserver(t *testing.T) {
+	providers := newTestProviders()
+	previous := &recordingObserver{}
+	config := &pinot.ClientConfig{BrokerList: []string{"localhost:8000"}, BrokerSelectorObserver: previous}
+	_, err := Instrument(config, providers.options...)
+	require.NoError(t, err)
+	conn, err := pinot.NewWithConfig(config)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	refreshErr := errors.New("zookeeper unavailable")
+	config.BrokerSelectorObserver.BrokersRefreshFailed(refreshErr)
+	assert.Equal(t, 1, previous.updates)
+	assert.Equal(t, []error{refreshErr}, previous.failures)
+
+	metrics := providers.collect(t)
+	brokers := metrics["pinot.client.broker_selector.brokers"].Data.(metricdata.Gauge[int64])
+	require.Len(t, brokers.DataPoints, 1)
+	assert.Equal(t, int64(1), brokers.DataPoints[0].Value)
+	assert.Equal(t, int64(1), metrics["pinot.client.broker_selector.refresh.errors"].Data.(metricdata.Sum[int64]).DataPoints[0].Value)
+}
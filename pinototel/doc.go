@@ -0,0 +1,17 @@
+// Package pinototel instruments Pinot connections with OpenTelemetry tracing and metrics.
+//
+// Queries get a span, parent of a client span per attempt on a broker, carrying the table, the broker,
+// the engine, the number of rows returned and the Pinot request id, and the W3C trace context is sent to
+// the broker as HTTP headers or gRPC metadata. The SQL of queries is recorded with WithQueryText only.
+// Metrics record the latency and errors of queries, the refresh failures of the broker lists and the
+// size of each broker list.
+//
+//	config := &pinot.ClientConfig{BrokerList: []string{"localhost:8000"}}
+//	if _, err := pinototel.Instrument(config); err != nil {
+//		return err
+//	}
+//	conn, err := pinot.NewWithConfig(config)
+//
+// The spans and metrics of the queries streamed with QueryStream or QueryArrow cover the opening of
+// their stream, not the consumption of their results.
+package pinototel
@@ -0,0 +1,140 @@
+package pinototel
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/startreedata/pinot-client-go/pinot"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "github.com/startreedata/pinot-client-go/pinototel"
+
+// Option configures the Instrumentation.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+	queryText      bool
+}
+
+// WithTracerProvider sets the provider of the tracer creating the query spans - defaults to the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider of the meter recording metrics - defaults to the global one.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator injecting the trace context into queries - defaults to
+// the W3C trace context propagator, sending the traceparent and tracestate headers.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// WithQueryText sets whether the query spans record the SQL of queries as db.query.text - defaults
+// to false, as queries may hold sensitive literals.
+func WithQueryText(enabled bool) Option {
+	return func(c *config) {
+		c.queryText = enabled
+	}
+}
+
+// Instrumentation traces the queries of a connection through its QueryInterceptor, traces and measures
+// their attempts through its Interceptor, and measures its broker lists as a pinot.BrokerSelectorObserver.
+// An Instrumentation is meant for a single connection, as it reports the broker lists of the last
+// connection notifying it. Instrument chains it to the observer already set on the connection config.
+type Instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	queryText  bool
+
+	queryDuration metric.Float64Histogram
+	queryErrors   metric.Int64Counter
+	refreshErrors metric.Int64Counter
+
+	mu sync.Mutex
+	// tableBrokers and allBrokers are the broker list sizes reported by the broker count gauge
+	tableBrokers map[string]int
+	allBrokers   int
+}
+
+// New creates the instruments of an Instrumentation.
+func New(opts ...Option) (*Instrumentation, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	meter := c.meterProvider.Meter(ScopeName)
+	i := &Instrumentation{
+		tracer:     c.tracerProvider.Tracer(ScopeName),
+		propagator: c.propagator,
+		queryText:  c.queryText,
+	}
+	var err error
+	i.queryDuration, err = meter.Float64Histogram("pinot.client.query.duration",
+		metric.WithDescription("Duration of the queries sent to brokers, per attempt"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query duration histogram: %w", err)
+	}
+	i.queryErrors, err = meter.Int64Counter("pinot.client.query.errors",
+		metric.WithDescription("Number of queries sent to brokers which failed or returned exceptions"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query error counter: %w", err)
+	}
+	i.refreshErrors, err = meter.Int64Counter("pinot.client.broker_selector.refresh.errors",
+		metric.WithDescription("Number of failed refreshes of the broker lists from Zookeeper or the controller"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh error counter: %w", err)
+	}
+	_, err = meter.Int64ObservableGauge("pinot.client.broker_selector.brokers",
+		metric.WithDescription("Number of brokers serving each table, or of the cluster when the table is not set"),
+		metric.WithUnit("{broker}"),
+		metric.WithInt64Callback(i.observeBrokers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create broker count gauge: %w", err)
+	}
+	return i, nil
+}
+
+// Instrument creates an Instrumentation and sets it up on config: its query interceptor and interceptor
+// are added as the outermost ones, so spans cover the other interceptors, which see the trace context
+// headers, and it observes the broker lists along with the BrokerSelectorObserver already set, if any.
+// The spans of the queries streamed with QueryStream or QueryArrow end once their stream is open, before
+// their results are consumed.
+func Instrument(config *pinot.ClientConfig, opts ...Option) (*Instrumentation, error) {
+	i, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	config.QueryInterceptors = append([]pinot.QueryInterceptor{i.QueryInterceptor()}, config.QueryInterceptors...)
+	config.Interceptors = append([]pinot.Interceptor{i.Interceptor()}, config.Interceptors...)
+	if config.BrokerSelectorObserver != nil {
+		config.BrokerSelectorObserver = chainedObserver{previous: config.BrokerSelectorObserver, next: i}
+	} else {
+		config.BrokerSelectorObserver = i
+	}
+	return i, nil
+}
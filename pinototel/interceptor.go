@@ -0,0 +1,162 @@
+package pinototel
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/startreedata/pinot-client-go/pinot"
+)
+
+// Attribute keys of the query spans and metrics.
+const (
+	DBSystemKey      = attribute.Key("db.system.name")
+	TableKey         = attribute.Key("db.collection.name")
+	QueryTextKey     = attribute.Key("db.query.text")
+	ReturnedRowsKey  = attribute.Key("db.response.returned_rows")
+	BrokerKey        = attribute.Key("server.address")
+	ErrorTypeKey     = attribute.Key("error.type")
+	EngineKey        = attribute.Key("pinot.engine")
+	RequestIDKey     = attribute.Key("pinot.request_id")
+	ClientQueryIDKey = attribute.Key("pinot.client_query_id")
+)
+
+// Values of EngineKey.
+const (
+	EngineSingleStage = "single-stage"
+	EngineMultistage  = "multistage"
+)
+
+// QueryInterceptor returns the query interceptor creating the span of each query, parent of the spans of
+// its attempts, to set in pinot.ClientConfig.QueryInterceptors.
+func (i *Instrumentation) QueryInterceptor() pinot.QueryInterceptor {
+	return func(next pinot.QueryFunc) pinot.QueryFunc {
+		return func(ctx context.Context, request *pinot.Request) (*pinot.BrokerResponse, error) {
+			return i.query(ctx, next, request)
+		}
+	}
+}
+
+// Interceptor returns the interceptor tracing and measuring each attempt of a query on a broker, to set
+// in pinot.ClientConfig.Interceptors.
+func (i *Instrumentation) Interceptor() pinot.Interceptor {
+	return func(next pinot.Transport) pinot.Transport {
+		return pinot.TransportFunc(func(ctx context.Context, brokerAddress string, request *pinot.Request) (*pinot.BrokerResponse, error) {
+			return i.execute(ctx, next, brokerAddress, request)
+		})
+	}
+}
+
+func (i *Instrumentation) query(ctx context.Context, next pinot.QueryFunc, request *pinot.Request) (*pinot.BrokerResponse, error) {
+	ctx, span := i.tracer.Start(ctx, "pinot.query",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(i.spanAttributes(request)...),
+		trace.WithAttributes(queryAttributes("", request)...))
+	defer span.End()
+
+	resp, err := next(ctx, request)
+	endSpan(span, resp, err)
+	return resp, err
+}
+
+func (i *Instrumentation) execute(ctx context.Context, next pinot.Transport, brokerAddress string, request *pinot.Request) (*pinot.BrokerResponse, error) {
+	attrs := queryAttributes(brokerAddress, request)
+	ctx, span := i.tracer.Start(ctx, "pinot.query.attempt",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(i.spanAttributes(request)...),
+		trace.WithAttributes(attrs...))
+	defer span.End()
+
+	carrier := propagation.MapCarrier{}
+	i.propagator.Inject(ctx, carrier)
+	for key, value := range carrier {
+		request = request.WithHeader(key, value)
+	}
+
+	start := time.Now()
+	resp, err := next.Execute(ctx, brokerAddress, request)
+	i.queryDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+
+	if errorType := endSpan(span, resp, err); errorType != "" {
+		i.queryErrors.Add(ctx, 1, metric.WithAttributes(append(attrs, ErrorTypeKey.String(errorType))...))
+	}
+	return resp, err
+}
+
+// spanAttributes returns the attributes of the query spans only, the query text being set when enabled.
+func (i *Instrumentation) spanAttributes(request *pinot.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{DBSystemKey.String("pinot"), ClientQueryIDKey.String(request.ClientQueryID())}
+	if i.queryText {
+		attrs = append(attrs, QueryTextKey.String(request.SQL()))
+	}
+	return attrs
+}
+
+// endSpan sets the outcome of a query on its span, returning the type of its error if it failed.
+func endSpan(span trace.Span, resp *pinot.BrokerResponse, err error) string {
+	if resp != nil {
+		if resp.RequestID != "" {
+			span.SetAttributes(RequestIDKey.String(resp.RequestID))
+		}
+		if resp.ResultTable != nil {
+			span.SetAttributes(ReturnedRowsKey.Int(resp.ResultTable.GetRowCount()))
+		}
+	}
+	failure := err
+	if failure == nil && resp != nil {
+		// Exceptions are reported whether or not the connection returns them as errors.
+		failure = resp.Err()
+	}
+	if failure == nil {
+		return ""
+	}
+	errorType := errorTypeOf(failure)
+	span.RecordError(failure)
+	span.SetStatus(codes.Error, failure.Error())
+	span.SetAttributes(ErrorTypeKey.String(errorType))
+	return errorType
+}
+
+// queryAttributes returns the attributes shared by the spans and the metrics of a query, the broker
+// being set on attempts only.
+func queryAttributes(brokerAddress string, request *pinot.Request) []attribute.KeyValue {
+	engine := EngineSingleStage
+	if request.UseMultistageEngine() {
+		engine = EngineMultistage
+	}
+	attrs := []attribute.KeyValue{EngineKey.String(engine)}
+	if brokerAddress != "" {
+		attrs = append(attrs, BrokerKey.String(brokerAddress))
+	}
+	if request.Table() != "" {
+		attrs = append(attrs, TableKey.String(request.Table()))
+	}
+	return attrs
+}
+
+// errorTypeOf classifies a query error: the HTTP status code of broker errors, the Pinot error code of
+// query exceptions, "timeout" or "canceled" for context errors, "_OTHER" otherwise.
+func errorTypeOf(err error) string {
+	var httpErr *pinot.BrokerHTTPError
+	if errors.As(err, &httpErr) {
+		return strconv.Itoa(httpErr.StatusCode)
+	}
+	var queryErr *pinot.QueryException
+	if errors.As(err, &queryErr) {
+		return "pinot_" + strconv.Itoa(queryErr.ErrorCode)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, pinot.ErrQueryTimeout):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "_OTHER"
+}
@@ -0,0 +1,327 @@
+package pinototel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"github.com/startreedata/pinot-client-go/pinot"
+	proto "github.com/startreedata/pinot-client-go/pinot/proto"
+)
+
+type testProviders struct {
+	spans   *tracetest.SpanRecorder
+	metrics *sdkmetric.ManualReader
+	options []Option
+}
+
+func newTestProviders() *testProviders {
+	spans := tracetest.NewSpanRecorder()
+	metrics := sdkmetric.NewManualReader()
+	return &testProviders{
+		spans:   spans,
+		metrics: metrics,
+		options: []Option{
+			WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+			WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics))),
+		},
+	}
+}
+
+// collect returns the metrics recorded so far by name.
+func (p *testProviders) collect(t *testing.T) map[string]metricdata.Metrics {
+	var data metricdata.ResourceMetrics
+	require.NoError(t, p.metrics.Collect(context.Background(), &data))
+	metrics := make(map[string]metricdata.Metrics)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+// spansByName returns the ended spans of each name, in the order they ended.
+func (p *testProviders) spansByName() map[string][]sdktrace.ReadOnlySpan {
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range p.spans.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	return spans
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestInterceptorHTTP(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"resultTable":{"dataSchema":{"columnDataTypes":["LONG"],"columnNames":["id"]},"rows":[[1],[2]]},"exceptions":[],"requestId":"42"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	providers := newTestProviders()
+	config := &pinot.ClientConfig{BrokerList: []string{ts.URL}}
+	_, err := Instrument(config, providers.options...)
+	require.NoError(t, err)
+	conn, err := pinot.NewWithConfig(config)
+	require.NoError(t, err)
+
+	_, err = conn.With(pinot.WithMultistage()).ExecuteSQL("baseballStats", "select id from baseballStats")
+	require.NoError(t, err)
+
+	spans := providers.spansByName()
+	require.Len(t, spans["pinot.query"], 1)
+	require.Len(t, spans["pinot.query.attempt"], 1)
+	query, attempt := spans["pinot.query"][0], spans["pinot.query.attempt"][0]
+	assert.Equal(t, trace.SpanKindInternal, query.SpanKind())
+	assert.Equal(t, trace.SpanKindClient, attempt.SpanKind())
+	assert.Equal(t, query.SpanContext().SpanID(), attempt.Parent().SpanID())
+	for _, span := range []sdktrace.ReadOnlySpan{query, attempt} {
+		attrs := spanAttributes(span)
+		assert.Equal(t, "baseballStats", attrs[TableKey].AsString())
+		assert.Equal(t, EngineMultistage, attrs[EngineKey].AsString())
+		assert.Equal(t, "42", attrs[RequestIDKey].AsString())
+		assert.Equal(t, int64(2), attrs[ReturnedRowsKey].AsInt64())
+		assert.NotContains(t, attrs, QueryTextKey)
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	}
+	assert.NotContains(t, spanAttributes(query), BrokerKey)
+	assert.Equal(t, ts.URL, spanAttributes(attempt)[BrokerKey].AsString())
+	assert.Equal(t, "00-"+attempt.SpanContext().TraceID().String()+"-"+attempt.SpanContext().SpanID().String()+"-01", traceparent)
+
+	metrics := providers.collect(t)
+	duration := metrics["pinot.client.query.duration"].Data.(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	table, _ := duration.DataPoints[0].Attributes.Value(TableKey)
+	assert.Equal(t, "baseballStats", table.AsString())
+	assert.NotContains(t, metrics, "pinot.client.query.errors")
+	brokers := metrics["pinot.client.broker_selector.brokers"].Data.(metricdata.Gauge[int64])
+	require.Len(t, brokers.DataPoints, 1)
+	assert.Equal(t, int64(1), brokers.DataPoints[0].Value)
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[{"errorCode":190,"message":"TableDoesNotExistError"}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	providers := newTestProviders()
+	config := &pinot.ClientConfig{
+		BrokerList: []string{ts.URL},
+		Interceptors: []pinot.Interceptor{func(next pinot.Transport) pinot.Transport {
+			return pinot.TransportFunc(func(ctx context.Context, brokerAddress string, request *pinot.Request) (*pinot.BrokerResponse, error) {
+				if request.SQL() == "fail" {
+					request = request.WithHeader("X-Fail", "true")
+				}
+				return next.Execute(ctx, brokerAddress, request)
+			})
+		}},
+	}
+	_, err := Instrument(config, providers.options...)
+	require.NoError(t, err)
+	conn, err := pinot.NewWithConfig(config)
+	require.NoError(t, err)
+
+	resp, err := conn.ExecuteSQL("missing", "select 1")
+	require.NoError(t, err)
+	assert.Len(t, resp.Exceptions, 1)
+	_, err = conn.ExecuteSQL("t", "fail")
+	var httpErr *pinot.BrokerHTTPError
+	require.ErrorAs(t, err, &httpErr)
+
+	spans := providers.spansByName()["pinot.query.attempt"]
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "pinot_190", spanAttributes(spans[0])[ErrorTypeKey].AsString())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "503", spanAttributes(spans[1])[ErrorTypeKey].AsString())
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+
+	errorCounts := make(map[string]int64)
+	for _, point := range providers.collect(t)["pinot.client.query.errors"].Data.(metricdata.Sum[int64]).DataPoints {
+		errorType, _ := point.Attributes.Value(ErrorTypeKey)
+		errorCounts[errorType.AsString()] += point.Value
+	}
+	assert.Equal(t, map[string]int64{"pinot_190": 1, "503": 1}, errorCounts)
+}
+
+// grpcBrokerServer records the requests it receives and answers with an empty result.
+type grpcBrokerServer struct {
+	proto.UnimplementedPinotQueryBrokerServer
+	requests chan *proto.BrokerRequest
+}
+
+func (s *grpcBrokerServer) Submit(req *proto.BrokerRequest, stream proto.PinotQueryBroker_SubmitServer) error {
+	s.requests <- req
+	return stream.Send(&proto.BrokerResponse{Payload: []byte(`{"exceptions":[],"requestId":"7"}`)})
+}
+
+func TestInterceptorGrpc(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	broker := &grpcBrokerServer{requests: make(chan *proto.BrokerRequest, 1)}
+	proto.RegisterPinotQueryBrokerServer(server, broker)
+	go func() {
+		if serveErr := server.Serve(listener); serveErr != nil && !errors.Is(serveErr, grpc.ErrServerStopped) {
+			assert.NoError(t, serveErr)
+		}
+	}()
+	defer server.Stop()
+
+	providers := newTestProviders()
+	config := &pinot.ClientConfig{
+		BrokerList: []string{listener.Addr().String()},
+		GrpcConfig: &pinot.GrpcConfig{Encoding: "JSON", Compression: "NONE"},
+	}
+	_, err = Instrument(config, providers.options...)
+	require.NoError(t, err)
+	conn, err := pinot.NewWithConfig(config)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecuteSQL("", "select 1")
+	require.NoError(t, err)
+	request := <-broker.requests
+	spans := providers.spansByName()["pinot.query.attempt"]
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01",
+		request.Metadata["traceparent"])
+	attrs := spanAttributes(span)
+	assert.Equal(t, "7", attrs[RequestIDKey].AsString())
+	assert.Equal(t, EngineSingleStage, attrs[EngineKey].AsString())
+	assert.NotContains(t, attrs, TableKey)
+
+	// Streamed queries are traced until their stream is open.
+	stream, err := conn.QueryStream(context.Background(), "", "select 1")
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	request = <-broker.requests
+	spans = providers.spansByName()["pinot.query.attempt"]
+	require.Len(t, spans, 2)
+	span = spans[1]
+	assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01",
+		request.Metadata["traceparent"])
+	assert.Equal(t, "7", spanAttributes(span)[RequestIDKey].AsString())
+	assert.Len(t, providers.spansByName()["pinot.query"], 2)
+}
+
+func TestInterceptorRetries(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[],"requestId":"43"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	providers := newTestProviders()
+	config := &pinot.ClientConfig{
+		BrokerList:  []string{ts.URL},
+		RetryPolicy: &pinot.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	}
+	_, err := Instrument(config, append(providers.options, WithQueryText(true))...)
+	require.NoError(t, err)
+	conn, err := pinot.NewWithConfig(config)
+	require.NoError(t, err)
+
+	_, err = conn.ExecuteSQL("t", "select 1")
+	require.NoError(t, err)
+
+	spans := providers.spansByName()
+	require.Len(t, spans["pinot.query"], 1)
+	require.Len(t, spans["pinot.query.attempt"], 2)
+	query := spans["pinot.query"][0]
+	assert.Equal(t, codes.Unset, query.Status().Code)
+	assert.Equal(t, "43", spanAttributes(query)[RequestIDKey].AsString())
+	assert.Equal(t, "select 1", spanAttributes(query)[QueryTextKey].AsString())
+	for _, attempt := range spans["pinot.query.attempt"] {
+		assert.Equal(t, query.SpanContext().SpanID(), attempt.Parent().SpanID())
+		assert.Equal(t, "select 1", spanAttributes(attempt)[QueryTextKey].AsString())
+	}
+	assert.Equal(t, codes.Error, spans["pinot.query.attempt"][0].Status().Code)
+	assert.Equal(t, "503", spanAttributes(spans["pinot.query.attempt"][0])[ErrorTypeKey].AsString())
+	assert.Equal(t, codes.Unset, spans["pinot.query.attempt"][1].Status().Code)
+
+	duration := providers.collect(t)["pinot.client.query.duration"].Data.(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(2), duration.DataPoints[0].Count)
+}
+
+func TestInterceptorPropagator(t *testing.T) {
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"exceptions":[]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	providers := newTestProviders()
+	config := &pinot.ClientConfig{BrokerList: []string{ts.URL}}
+	_, err := Instrument(config, append(providers.options, WithPropagator(propagation.Baggage{}))...)
+	require.NoError(t, err)
+	conn, err := pinot.NewWithConfig(config)
+	require.NoError(t, err)
+
+	member, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+	_, err = conn.ExecuteSQLContext(baggage.ContextWithBaggage(context.Background(), bag), "t", "select 1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "tenant=acme", header.Get("baggage"))
+	assert.Empty(t, header.Get("traceparent"))
+}
+
+func TestErrorTypeOf(t *testing.T) {
+	assert.Equal(t, "timeout", errorTypeOf(context.DeadlineExceeded))
+	assert.Equal(t, "504", errorTypeOf(&pinot.BrokerHTTPError{StatusCode: http.StatusGatewayTimeout}))
+	assert.Equal(t, "timeout", errorTypeOf(fmt.Errorf("query failed: %w", pinot.ErrQueryTimeout)))
+	assert.Equal(t, "canceled", errorTypeOf(context.Canceled))
+	assert.Equal(t, "_OTHER", errorTypeOf(errors.New("boom")))
+}